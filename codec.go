@@ -0,0 +1,295 @@
+package goapi
+
+import (
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/NaturalSelectionLabs/goapi/lib/openapi"
+	"github.com/NaturalSelectionLabs/jschema"
+)
+
+// Decoder decodes the request body into v, v is a pointer to the param of the handler.
+// Check [Router.AddDecoder].
+type Decoder interface {
+	Decode(r io.Reader, v any) error
+}
+
+// DecoderFunc is an adapter to allow the use of ordinary functions as [Decoder].
+type DecoderFunc func(r io.Reader, v any) error
+
+// Decode implements the [Decoder] interface.
+func (fn DecoderFunc) Decode(r io.Reader, v any) error {
+	return fn(r, v)
+}
+
+// DecoderJSON is the default [Decoder] for "application/json".
+var DecoderJSON = DecoderFunc(func(r io.Reader, v any) error {
+	return json.NewDecoder(r).Decode(v)
+})
+
+type bodyDecoder struct {
+	contentType string
+	decoder     Decoder
+}
+
+// name returns the short name of the content type, such as "json" for "application/json"
+// or "application/problem+json".
+func (d *bodyDecoder) name() string {
+	n := d.contentType[strings.LastIndex(d.contentType, "/")+1:]
+	return n[strings.LastIndex(n, "+")+1:]
+}
+
+// AddDecoder registers a [Decoder] for the request body of the content type, such as "application/xml".
+// The decoder is selected by the Content-Type header of the request, the body is still validated by the json schema
+// of the param, a request with an unregistered content type will be responded with 415.
+// The first decoder is used when the request has no Content-Type header, it's [DecoderJSON] by default.
+// Adding a decoder for a registered content type replaces it.
+func (r *Router) AddDecoder(contentType string, d Decoder) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list := []*bodyDecoder{}
+	replaced := false
+
+	for _, bd := range *r.decoders.Load() {
+		if bd.contentType == contentType {
+			bd = &bodyDecoder{contentType, d}
+			replaced = true
+		}
+
+		list = append(list, bd)
+	}
+
+	if !replaced {
+		list = append(list, &bodyDecoder{contentType, d})
+	}
+
+	r.decoders.Store(&list)
+}
+
+// decoder returns the decoder for the Content-Type header of a request to the param,
+// nil if there's no decoder for it.
+func (r *Router) decoder(param reflect.Type, header string) *bodyDecoder {
+	list := *r.decoders.Load()
+
+	if header == "" {
+		return list[0]
+	}
+
+	t, _, err := mime.ParseMediaType(header)
+	if err != nil {
+		return nil
+	}
+
+	// The param with a custom content type is decoded by the default decoder.
+	if t == getContentType(param, "") {
+		return list[0]
+	}
+
+	for _, d := range list {
+		if d.contentType == t {
+			return d
+		}
+	}
+
+	return nil
+}
+
+// Encoder encodes the response body, v is the value that [EncoderJSON] would marshal,
+// such as the [openapi.ResponseFormatData] or the Data of a direct response.
+// Check [Router.AddEncoder].
+type Encoder interface {
+	Encode(w io.Writer, v any) error
+}
+
+// EncoderFunc is an adapter to allow the use of ordinary functions as [Encoder].
+type EncoderFunc func(w io.Writer, v any) error
+
+// Encode implements the [Encoder] interface.
+func (fn EncoderFunc) Encode(w io.Writer, v any) error {
+	return fn(w, v)
+}
+
+// EncoderJSON is the default [Encoder] for "application/json".
+var EncoderJSON = EncoderFunc(func(w io.Writer, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	_, err = w.Write(b)
+
+	return err
+})
+
+type bodyEncoder struct {
+	contentType string
+	encoder     Encoder
+}
+
+// AddEncoder registers an [Encoder] for the response body of the content type, such as "application/xml".
+// The encoder is selected by the Accept header of the request, a request that accepts none of the encoders
+// will be responded with 406, unless the response is an error, then the first encoder is used.
+// The first encoder is used when the request has no Accept header, it's [EncoderJSON] by default.
+// Adding an encoder for a registered content type replaces it.
+func (r *Router) AddEncoder(contentType string, e Encoder) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list := []*bodyEncoder{}
+	replaced := false
+
+	for _, be := range *r.encoders.Load() {
+		if be.contentType == contentType {
+			be = &bodyEncoder{contentType, e}
+			replaced = true
+		}
+
+		list = append(list, be)
+	}
+
+	if !replaced {
+		list = append(list, &bodyEncoder{contentType, e})
+	}
+
+	r.encoders.Store(&list)
+}
+
+// encoder returns the encoder with the highest quality in the Accept header of a request,
+// the earlier registered one wins when the qualities are equal, nil if none is acceptable.
+func (r *Router) encoder(accept string) *bodyEncoder {
+	list := *r.encoders.Load()
+
+	types := make([]string, 0, len(list))
+	for _, e := range list {
+		types = append(types, e.contentType)
+	}
+
+	if i := negotiate(accept, types); i >= 0 {
+		return list[i]
+	}
+
+	return nil
+}
+
+// addVary adds the name to the Vary header if it's not listed yet.
+func addVary(h http.Header, name string) {
+	for _, v := range h.Values("Vary") {
+		for _, item := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(item), name) {
+				return
+			}
+		}
+	}
+
+	h.Add("Vary", name)
+}
+
+// negotiate returns the index of the content type with the highest quality in the Accept header,
+// the earlier one wins when the qualities are equal, -1 if none is acceptable.
+// The first one is returned if the Accept header is empty.
+func negotiate(accept string, types []string) int {
+	if strings.TrimSpace(accept) == "" {
+		return 0
+	}
+
+	type mediaRange struct {
+		typ string
+		q   float64
+	}
+
+	ranges := []mediaRange{}
+
+	for _, s := range strings.Split(accept, ",") {
+		t, params, err := mime.ParseMediaType(s)
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+
+		if v, has := params["q"]; has {
+			q, err = strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+		}
+
+		ranges = append(ranges, mediaRange{t, q})
+	}
+
+	// specificity of the media range that matches t, 0 if it doesn't match.
+	specificity := func(rng, t string) int {
+		switch {
+		case rng == t:
+			return 3
+		case strings.HasSuffix(rng, "/*") && strings.HasPrefix(t, rng[:len(rng)-1]):
+			return 2
+		case rng == "*/*":
+			return 1
+		default:
+			return 0
+		}
+	}
+
+	best, bestQ := -1, 0.0
+
+	for i, t := range types {
+		q, level := 0.0, 0
+
+		for _, rng := range ranges {
+			if l := specificity(rng.typ, t); l > level {
+				q, level = rng.q, l
+			}
+		}
+
+		if q > bestQ {
+			best, bestQ = i, q
+		}
+	}
+
+	return best
+}
+
+// resContent returns the openapi content of all the encoders for the response body of t.
+func (r *Router) resContent(t reflect.Type, scm *jschema.Schema) *openapi.Content {
+	content := openapi.Content{}
+
+	for i, e := range *r.encoders.Load() {
+		ct := e.contentType
+
+		if i == 0 {
+			ct = getContentType(t, ct)
+		}
+
+		content[ct] = &openapi.Schema{
+			Schema: scm,
+		}
+	}
+
+	return &content
+}
+
+// bodyContent returns the openapi content of all the decoders for the request body of the param.
+func (r *Router) bodyContent(s jschema.Schemas, param reflect.Type) *openapi.Content {
+	content := openapi.Content{}
+
+	for i, d := range *r.decoders.Load() {
+		t := d.contentType
+
+		if i == 0 {
+			t = getContentType(param, t)
+		}
+
+		content[t] = &openapi.Schema{
+			Schema: s.DefineT(param),
+		}
+	}
+
+	return &content
+}
@@ -0,0 +1,191 @@
+package goapi_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/NaturalSelectionLabs/goapi"
+	"github.com/NaturalSelectionLabs/goapi/lib/middlewares"
+	"github.com/NaturalSelectionLabs/goapi/lib/openapi"
+	"github.com/ysmood/got"
+)
+
+type xmlUser struct {
+	XMLName xml.Name `json:"-" xml:"user"`
+	Name    string   `json:"name" xml:"name" minLen:"2"`
+}
+
+func TestDecoder(t *testing.T) {
+	g := got.T(t)
+
+	r := goapi.New()
+
+	r.Router().AddDecoder("application/xml", goapi.DecoderFunc(func(r io.Reader, v any) error {
+		return xml.NewDecoder(r).Decode(v)
+	}))
+
+	r.POST("/users", func(u xmlUser) resOK {
+		return resOK{Data: u.Name}
+	})
+
+	tr := g.Serve()
+	tr.Mux.Handle("/", r.Server())
+
+	post := func(contentType, body string) (int, string) {
+		res, err := http.Post(tr.URL("/users"), contentType, bytes.NewBufferString(body))
+		g.E(err)
+
+		return res.StatusCode, g.Read(res.Body).String()
+	}
+
+	code, body := post("application/xml; charset=utf-8", "<user><name>jack</name></user>")
+	g.Eq(code, http.StatusOK)
+	g.Eq(body, `{"data":"jack"}`)
+
+	code, body = post("application/json", `{"name": "tom"}`)
+	g.Eq(code, http.StatusOK)
+	g.Eq(body, `{"data":"tom"}`)
+
+	code, body = post("", `{"name": "tom"}`)
+	g.Eq(code, http.StatusOK)
+	g.Eq(body, `{"data":"tom"}`)
+
+	code, body = post("application/xml", "<user><name>j</name></user>")
+	g.Eq(code, http.StatusBadRequest)
+	g.Has(body, "request body is invalid")
+
+	code, body = post("application/xml", "<user>")
+	g.Eq(code, http.StatusBadRequest)
+	g.Has(body, "failed to parse xml body")
+
+	code, body = post("text/csv", "name\njack")
+	g.Eq(code, http.StatusUnsupportedMediaType)
+	g.Eq(body, `{"error":{"code":"unsupported_media_type","message":"unsupported media type: text/csv",`+
+		`"target":"Content-Type"}}`+"\n")
+
+	content := *r.OpenAPI().Paths["/users"][openapi.POST].RequestBody.Content
+	g.Len(content, 2)
+	g.Eq(content["application/xml"].Schema, content[openapi.ContentTypeJSON].Schema)
+}
+
+type resUsers interface {
+	goapi.Response
+}
+
+var _ = goapi.Interface(new(resUsers), resUsersOK{}, resUsersErr{})
+
+type resUsersOK struct {
+	goapi.StatusOK
+	Data []string
+}
+
+type resUsersErr struct {
+	goapi.StatusBadRequest
+	Error openapi.Error
+}
+
+func TestEncoder(t *testing.T) {
+	g := got.T(t)
+
+	r := goapi.New()
+
+	r.Router().AddEncoder("application/xml", goapi.EncoderFunc(func(w io.Writer, v any) error {
+		return xml.NewEncoder(w).Encode(v)
+	}))
+
+	r.Router().AddEncoder("text/csv", goapi.EncoderFunc(func(w io.Writer, v any) error {
+		list, ok := v.(openapi.ResponseFormatData).Data.([]string)
+		if !ok {
+			return errors.New("csv only supports list data")
+		}
+
+		return csv.NewWriter(w).WriteAll([][]string{list})
+	}))
+
+	r.GET("/users", func(p struct {
+		goapi.InURL
+		Fail bool `default:"false"`
+	}) resUsers {
+		if p.Fail {
+			return resUsersErr{Error: openapi.Error{Message: "fail"}}
+		}
+
+		return resUsersOK{Data: []string{"a", "b"}}
+	})
+
+	r.GET("/vary", func() resUsers {
+		return resUsersOK{Data: []string{"a"}}
+	}).Use(middlewares.Func(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("accept") == "true" {
+				w.Header().Set("Vary", "Origin, accept")
+			} else {
+				w.Header().Set("Vary", "Origin")
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}))
+
+	tr := g.Serve()
+	tr.Mux.Handle("/", r.Server())
+
+	get := func(path, accept string) (int, string, string) {
+		req, err := http.NewRequest(http.MethodGet, tr.URL(path), nil)
+		g.E(err)
+
+		req.Header.Set("Accept", accept)
+
+		res, err := http.DefaultClient.Do(req)
+		g.E(err)
+
+		return res.StatusCode, res.Header.Get("Content-Type"), g.Read(res.Body).String()
+	}
+
+	code, ct, body := get("/users", "")
+	g.Eq(code, http.StatusOK)
+	g.Eq(ct, "application/json; charset=utf-8")
+	g.Eq(body, `{"data":["a","b"]}`)
+
+	res, err := http.Get(tr.URL("/users"))
+	g.E(err)
+	g.Eq(res.Header.Values("Vary"), []string{"Accept"})
+
+	res, err = http.Get(tr.URL("/vary"))
+	g.E(err)
+	g.Eq(res.Header.Values("Vary"), []string{"Origin", "Accept"})
+
+	res, err = http.Get(tr.URL("/vary?accept=true"))
+	g.E(err)
+	g.Eq(res.Header.Values("Vary"), []string{"Origin, accept"})
+
+	_, ct, body = get("/users", "application/xml")
+	g.Eq(ct, "application/xml")
+	g.Eq(body, `<ResponseFormatData><Data>a</Data><Data>b</Data></ResponseFormatData>`)
+
+	_, ct, body = get("/users", "text/csv;q=0.5, application/*;q=0.8")
+	g.Eq(ct, "application/json; charset=utf-8")
+	g.Eq(body, `{"data":["a","b"]}`)
+
+	_, ct, body = get("/users", "text/*, application/json;q=0.1")
+	g.Eq(ct, "text/csv")
+	g.Eq(body, "a,b\n")
+
+	code, _, body = get("/users", "image/png, application/json;q=0")
+	g.Eq(code, http.StatusNotAcceptable)
+	g.Eq(body, `{"error":{"code":"not_acceptable","message":"not acceptable: image/png, application/json;q=0",`+
+		`"target":"Accept"}}`+"\n")
+
+	code, ct, _ = get("/users?fail=true", "image/png")
+	g.Eq(code, http.StatusBadRequest)
+	g.Eq(ct, "application/json; charset=utf-8")
+
+	doc := r.OpenAPI().Paths["/users"][openapi.GET].Responses[openapi.StatusOK]
+	g.Len(*doc.Content, 3)
+	g.Eq((*doc.Content)["text/csv"].Schema, (*doc.Content)[openapi.ContentTypeJSON].Schema)
+}
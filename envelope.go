@@ -0,0 +1,111 @@
+package goapi
+
+import (
+	"net/http"
+	"reflect"
+
+	"github.com/NaturalSelectionLabs/goapi/lib/openapi"
+	"github.com/NaturalSelectionLabs/jschema"
+)
+
+// Envelope wraps the Data, Meta, and Error fields of the responses as the response body, check [Router.Envelope].
+// The responses with the `response:"direct"` tag and the [DataStream] responses are not wrapped.
+type Envelope interface {
+	// Wrap returns the response body of the parts.
+	Wrap(r *http.Request, p EnvelopeParts) any
+
+	// Schema returns the schema of the response body for the openapi doc.
+	Schema(p EnvelopeSchemas) *jschema.Schema
+}
+
+// EnvelopeParts are the field values of a response to wrap.
+type EnvelopeParts struct {
+	// Status is the status code of the response.
+	Status int
+
+	Data any
+
+	// Meta is nil if HasMeta is false.
+	Meta    any
+	HasMeta bool
+
+	// Error is nil if IsError is false, Data is nil if IsError is true.
+	Error   any
+	IsError bool
+}
+
+// EnvelopeSchemas are the schemas of the fields of a response to wrap, the schema of a missing field is nil.
+type EnvelopeSchemas struct {
+	Data  *jschema.Schema
+	Meta  *jschema.Schema
+	Error *jschema.Schema
+}
+
+// DefaultEnvelope is the default [Envelope], it wraps the response as {"data": data, "meta": meta}
+// or {"error": err}.
+type DefaultEnvelope struct{}
+
+var _ Envelope = DefaultEnvelope{}
+
+// Wrap implements the [Envelope] interface.
+func (DefaultEnvelope) Wrap(_ *http.Request, p EnvelopeParts) any {
+	switch {
+	case p.IsError:
+		return openapi.ResponseFormatErr{Error: p.Error}
+	case p.HasMeta:
+		return openapi.ResponseFormatMeta{Data: p.Data, Meta: p.Meta}
+	default:
+		return openapi.ResponseFormatData{Data: p.Data}
+	}
+}
+
+// Schema implements the [Envelope] interface.
+func (DefaultEnvelope) Schema(p EnvelopeSchemas) *jschema.Schema {
+	scm := &jschema.Schema{
+		Type:                 jschema.TypeObject,
+		AdditionalProperties: ptr(false),
+		Properties:           jschema.Properties{},
+	}
+
+	if p.Error != nil {
+		scm.Properties["error"] = p.Error
+		scm.Required = []string{"error"}
+
+		return scm
+	}
+
+	scm.Properties["data"] = p.Data
+	scm.Required = []string{"data"}
+
+	if p.Meta != nil {
+		scm.Properties["meta"] = p.Meta
+		scm.Required = append(scm.Required, "meta")
+	}
+
+	return scm
+}
+
+// envelope returns the [Router.Envelope], or the [DefaultEnvelope] if it's nil.
+func (r *Router) envelope() Envelope {
+	if r.Envelope != nil {
+		return r.Envelope
+	}
+
+	return DefaultEnvelope{}
+}
+
+// envelopeErrorFormatter formats the errors written by the router with the [Router.Envelope],
+// it's used when the [Router.ErrorFormatter] is nil.
+type envelopeErrorFormatter struct {
+	envelope Envelope
+}
+
+// FormatError implements the [middlewares.ErrorFormatter] interface.
+func (f envelopeErrorFormatter) FormatError(r *http.Request, code int, err any) (string, any) {
+	return "application/json; charset=utf-8", f.envelope.Wrap(r, EnvelopeParts{Status: code, Error: err, IsError: true})
+}
+
+// ErrorSchema implements the [ErrorFormatter] interface.
+func (f envelopeErrorFormatter) ErrorSchema(s jschema.Schemas, t reflect.Type) (string, *jschema.Schema) {
+	return openapi.ContentTypeJSON, f.envelope.Schema(EnvelopeSchemas{Error: s.DefineT(t)})
+}
@@ -0,0 +1,105 @@
+package goapi_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/NaturalSelectionLabs/goapi"
+	"github.com/NaturalSelectionLabs/goapi/lib/openapi"
+	"github.com/NaturalSelectionLabs/jschema"
+	"github.com/ysmood/got"
+)
+
+type successEnvelope struct{}
+
+func (successEnvelope) Wrap(_ *http.Request, p goapi.EnvelopeParts) any {
+	if p.IsError {
+		return map[string]any{"success": false, "error": p.Error}
+	}
+
+	body := map[string]any{"success": true, "result": p.Data}
+
+	if p.HasMeta {
+		body["pagination"] = p.Meta
+	}
+
+	return body
+}
+
+func (successEnvelope) Schema(p goapi.EnvelopeSchemas) *jschema.Schema {
+	scm := &jschema.Schema{
+		Type: jschema.TypeObject,
+		Properties: jschema.Properties{
+			"success": {Type: jschema.TypeBool},
+		},
+		Required: []string{"success"},
+	}
+
+	if p.Error != nil {
+		scm.Properties["error"] = p.Error
+		return scm
+	}
+
+	scm.Properties["result"] = p.Data
+
+	if p.Meta != nil {
+		scm.Properties["pagination"] = p.Meta
+	}
+
+	return scm
+}
+
+type resPage struct {
+	goapi.StatusOK
+	Data []string
+	Meta int
+}
+
+func TestEnvelope(t *testing.T) {
+	g := got.T(t)
+
+	r := goapi.New()
+	r.Router().Envelope = successEnvelope{}
+
+	r.GET("/items", func(p struct {
+		goapi.InURL
+		Page int `min:"1" default:"1"`
+	}) resPage {
+		return resPage{Data: []string{"a"}, Meta: p.Page}
+	})
+
+	r.GET("/fail", func() resErr {
+		return resErr{Error: openapi.Error{Message: "fail"}}
+	})
+
+	tr := g.Serve()
+	tr.Mux.Handle("/", r.Server())
+
+	g.Eq(g.Req("", tr.URL("/items?page=2")).JSON(), map[string]any{
+		"success":    true,
+		"result":     []any{"a"},
+		"pagination": 2.0,
+	})
+
+	g.Eq(g.Req("", tr.URL("/fail")).JSON(), map[string]any{
+		"success": false,
+		"error":   map[string]any{"code": "not_found", "message": "fail"},
+	})
+
+	res := g.Req("", tr.URL("/items?page=0"))
+	g.Eq(res.StatusCode, http.StatusBadRequest)
+	g.Eq(res.JSON().(map[string]any)["success"], false)
+
+	res = g.Req("", tr.URL("/x"))
+	g.Eq(res.StatusCode, http.StatusNotFound)
+	g.Eq(res.JSON().(map[string]any)["success"], false)
+
+	doc := r.OpenAPI().Paths["/items"][openapi.GET].Responses
+
+	scm := (*doc[openapi.StatusOK].Content)[openapi.ContentTypeJSON].Schema
+	g.Eq(scm.Required, jschema.Required{"success"})
+	g.Eq(scm.Properties["pagination"].Type, jschema.TypeInteger)
+
+	scm = (*doc[openapi.StatusBadRequest].Content)[openapi.ContentTypeJSON].Schema
+	g.Eq(scm.Properties["error"].Ref.ID, "Error")
+}
@@ -0,0 +1,125 @@
+package goapi
+
+import (
+	"net/http"
+	"reflect"
+
+	"github.com/NaturalSelectionLabs/goapi/lib/middlewares"
+	"github.com/NaturalSelectionLabs/goapi/lib/openapi"
+	"github.com/NaturalSelectionLabs/jschema"
+)
+
+// ErrorFormatter formats all the error responses of a router, check [Router.ErrorFormatter].
+type ErrorFormatter interface {
+	middlewares.ErrorFormatter
+
+	// ErrorSchema returns the content type and the schema of the error response body for the openapi doc,
+	// t is the type of the error value.
+	ErrorSchema(s jschema.Schemas, t reflect.Type) (contentType string, scm *jschema.Schema)
+}
+
+var tOpenAPIError = reflect.TypeOf(openapi.Error{})
+
+// ErrorEnvelope is the default [ErrorFormatter], it formats an error as {"error": err}.
+type ErrorEnvelope struct{}
+
+var _ ErrorFormatter = ErrorEnvelope{}
+
+// FormatError implements the [middlewares.ErrorFormatter] interface.
+func (ErrorEnvelope) FormatError(_ *http.Request, _ int, err any) (string, any) {
+	return "application/json; charset=utf-8", openapi.ResponseFormatErr{Error: err}
+}
+
+// ErrorSchema implements the [ErrorFormatter] interface.
+func (ErrorEnvelope) ErrorSchema(s jschema.Schemas, t reflect.Type) (string, *jschema.Schema) {
+	return openapi.ContentTypeJSON, &jschema.Schema{
+		Type:                 jschema.TypeObject,
+		AdditionalProperties: ptr(false),
+		Properties: jschema.Properties{
+			"error": s.DefineT(t),
+		},
+		Required: []string{"error"},
+	}
+}
+
+// ProblemDetails is an [ErrorFormatter] that formats an error as the RFC 9457 "application/problem+json".
+// The fields of an [openapi.Error] become the detail and the extension members of the [openapi.Problem],
+// other error values become the "innererror" extension member.
+type ProblemDetails struct {
+	// TypeBase is the prefix of the problem type, the code of the [openapi.Error] is appended to it,
+	// such as "https://example.com/problems/not_found". The type is "about:blank" if it's empty.
+	TypeBase string
+}
+
+var _ ErrorFormatter = ProblemDetails{}
+
+// FormatError implements the [middlewares.ErrorFormatter] interface.
+func (p ProblemDetails) FormatError(r *http.Request, code int, err any) (string, any) {
+	problem := openapi.Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(code),
+		Status:   code,
+		Instance: originalPath(r),
+	}
+
+	if e, ok := err.(*openapi.Error); ok && e != nil {
+		err = *e
+	}
+
+	switch e := err.(type) {
+	case openapi.Error:
+		if p.TypeBase != "" {
+			problem.Type = p.TypeBase + e.Code.String()
+		}
+
+		problem.Detail = e.Message
+		problem.Code = &e.Code
+		problem.Target = e.Target
+		problem.Errors = e.Details
+		problem.InnerError = e.InnerError
+
+	case error:
+		problem.Detail = e.Error()
+		problem.InnerError = e
+
+	default:
+		problem.InnerError = e
+	}
+
+	return openapi.ContentTypeProblem, problem
+}
+
+// ErrorSchema implements the [ErrorFormatter] interface.
+func (ProblemDetails) ErrorSchema(s jschema.Schemas, _ reflect.Type) (string, *jschema.Schema) {
+	return openapi.ContentTypeProblem, s.DefineT(reflect.TypeOf(openapi.Problem{}))
+}
+
+// errorFormatter returns the [Router.ErrorFormatter]. If it's nil, the errors are wrapped by the [Router.Envelope],
+// or the [ErrorEnvelope] if both are nil.
+func (r *Router) errorFormatter() ErrorFormatter {
+	if r.ErrorFormatter != nil {
+		return r.ErrorFormatter
+	}
+
+	if r.Envelope != nil {
+		return envelopeErrorFormatter{r.Envelope}
+	}
+
+	return ErrorEnvelope{}
+}
+
+// writeError writes the error response with the error formatter of the router.
+func (r *Router) writeError(w http.ResponseWriter, rq *http.Request, code int, err any) {
+	middlewares.WriteError(w, middlewares.WithErrorFormatter(rq, r.errorFormatter()), code, err)
+}
+
+// errorContent returns the openapi content of the error responses of the error type t.
+func (r *Router) errorContent(s jschema.Schemas, t reflect.Type) *openapi.Content {
+	contentType, scm := r.errorFormatter().ErrorSchema(s, t)
+
+	return &openapi.Content{
+		contentType: &openapi.Schema{
+			Schema: scm,
+		},
+	}
+}
@@ -0,0 +1,95 @@
+package goapi_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/NaturalSelectionLabs/goapi"
+	"github.com/NaturalSelectionLabs/goapi/lib/middlewares/calm"
+	"github.com/NaturalSelectionLabs/goapi/lib/openapi"
+	"github.com/ysmood/got"
+)
+
+func TestProblemDetails(t *testing.T) {
+	g := got.T(t)
+
+	r := goapi.New()
+	r.Router().ErrorFormatter = goapi.ProblemDetails{TypeBase: "https://example.com/problems/"}
+
+	c := calm.New()
+	c.PrintStack = false
+	r.Use(c)
+
+	r.GET("/users/{id}", func(p struct {
+		goapi.InURL
+		ID int `min:"1"`
+	}) resErr {
+		if p.ID == 1 {
+			panic("boom")
+		}
+
+		return resErr{Error: openapi.Error{Code: openapi.CodeNotFound, Message: "no user"}}
+	})
+
+	tr := g.Serve()
+	tr.Mux.Handle("/", r.Server())
+
+	res := g.Req("", tr.URL("/users/2"))
+	g.Eq(res.StatusCode, http.StatusBadRequest)
+	g.Eq(res.Header.Get("Content-Type"), openapi.ContentTypeProblem)
+	g.Eq(res.JSON(), map[string]any{
+		"type":     "https://example.com/problems/not_found",
+		"title":    "Bad Request",
+		"status":   400.0,
+		"detail":   "no user",
+		"instance": "/users/2",
+		"code":     "not_found",
+	})
+
+	res = g.Req("", tr.URL("/users/0"))
+	g.Eq(res.StatusCode, http.StatusBadRequest)
+	g.Eq(res.JSON(), map[string]any{
+		"type":     "https://example.com/problems/invalid_param",
+		"title":    "Bad Request",
+		"status":   400.0,
+		"detail":   "param `id` is invalid: Must be greater than or equal to 1",
+		"instance": "/users/0",
+		"code":     "invalid_param",
+		"errors": []any{map[string]any{
+			"code":    "min",
+			"message": "param `id` is invalid: Must be greater than or equal to 1",
+			"target":  "path:id",
+		}},
+	})
+
+	res = g.Req("", tr.URL("/users/1"))
+	g.Eq(res.StatusCode, http.StatusInternalServerError)
+	g.Eq(res.Header.Get("Content-Type"), openapi.ContentTypeProblem)
+	g.Eq(res.JSON().(map[string]any)["detail"], "boom")
+
+	res = g.Req("", tr.URL("/x"))
+	g.Eq(res.StatusCode, http.StatusNotFound)
+	g.Eq(res.JSON().(map[string]any)["detail"], "path not found: GET /x")
+
+	doc := r.OpenAPI().Paths["/users/{id}"][openapi.GET].Responses
+	scm := (*doc[openapi.StatusBadRequest].Content)[openapi.ContentTypeProblem].Schema
+	g.Eq(scm.Ref.ID, "Problem")
+	g.Nil((*doc[openapi.StatusBadRequest].Content)[openapi.ContentTypeJSON])
+}
+
+func TestProblemDetailsFormat(t *testing.T) {
+	g := got.T(t)
+
+	req, err := http.NewRequest(http.MethodGet, "/a", nil)
+	g.E(err)
+
+	ct, body := goapi.ProblemDetails{}.FormatError(req, http.StatusConflict, "conflict")
+	g.Eq(ct, openapi.ContentTypeProblem)
+	g.Eq(body, openapi.Problem{
+		Type:       "about:blank",
+		Title:      "Conflict",
+		Status:     http.StatusConflict,
+		Instance:   "/a",
+		InnerError: "conflict",
+	})
+}
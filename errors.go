@@ -0,0 +1,120 @@
+package goapi
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"reflect"
+
+	"github.com/NaturalSelectionLabs/goapi/lib/openapi"
+	"github.com/naturalselectionlabs/vary"
+)
+
+var tError = reflect.TypeOf((*error)(nil)).Elem()
+
+// errorMapping converts the errors returned by handlers to responses, check [MapError].
+type errorMapping struct {
+	// convert returns false if the error doesn't match.
+	convert func(err error) (Response, bool)
+
+	// tRes is the response type for the openapi doc.
+	tRes reflect.Type
+}
+
+// MapError maps the errors returned by the handlers of the router to the response created by fn,
+// when errors.Is(err, target) is true. A handler can return an error as the second return value, such as:
+//
+//	func(p Params) (Res, error)
+//
+// The mappings are checked in the order they are added, an unmapped error is responded as 500
+// with [openapi.CodeInternalError] and a generic message, the error itself is logged by [Router.Logger].
+// The possible responses of the mappings are added to the openapi doc of each operation that returns an error,
+// the mappings of the same status code share one response doc.
+func MapError[R Response](r *Router, target error, fn func(err error) R) {
+	r.addErrorMapping(&errorMapping{
+		convert: func(err error) (Response, bool) {
+			if errors.Is(err, target) {
+				return fn(err), true
+			}
+
+			return nil, false
+		},
+		tRes: reflect.TypeOf((*R)(nil)).Elem(),
+	})
+}
+
+// MapErrorAs is like [MapError], but it uses errors.As to match the error type E, such as:
+//
+//	MapErrorAs(r, func(err *fs.PathError) NotFound { return NotFound{} })
+func MapErrorAs[E error, R Response](r *Router, fn func(err E) R) {
+	r.addErrorMapping(&errorMapping{
+		convert: func(err error) (Response, bool) {
+			var target E
+			if errors.As(err, &target) {
+				return fn(target), true
+			}
+
+			return nil, false
+		},
+		tRes: reflect.TypeOf((*R)(nil)).Elem(),
+	})
+}
+
+func (r *Router) addErrorMapping(m *errorMapping) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list := append([]*errorMapping{}, *r.errorMappings.Load()...)
+	list = append(list, m)
+	r.errorMappings.Store(&list)
+}
+
+// mapError returns nil if no mapping matches the err.
+func (r *Router) mapError(err error) Response {
+	for _, m := range *r.errorMappings.Load() {
+		if res, ok := m.convert(err); ok {
+			return res
+		}
+	}
+
+	return nil
+}
+
+// errorResTypes returns the response types of all the error mappings,
+// the implementations of an interface are expanded.
+func (r *Router) errorResTypes() []reflect.Type {
+	list := []reflect.Type{}
+
+	for _, m := range *r.errorMappings.Load() {
+		if it, has := Interfaces[vary.ID(m.tRes)]; has {
+			for _, t := range it.Implementations {
+				list = append(list, t)
+			}
+		} else {
+			list = append(list, m.tRes)
+		}
+	}
+
+	return list
+}
+
+// internalError logs the unmapped error returned by a handler and returns the response of it,
+// the error message is not exposed to the client.
+func (r *Router) internalError(rq *http.Request, err error) *openapi.Error {
+	r.logError(rq, err)
+
+	return &openapi.Error{
+		Code:    openapi.CodeInternalError,
+		Message: "internal server error",
+	}
+}
+
+// logError logs the unmapped error returned by a handler with the [Router.Logger].
+func (r *Router) logError(rq *http.Request, err error) {
+	logger := r.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+
+	logger.ErrorContext(rq.Context(), "unmapped handler error", "method", rq.Method, "path", rq.URL.Path, "err", err)
+}
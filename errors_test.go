@@ -0,0 +1,128 @@
+package goapi_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"net/http"
+	"testing"
+
+	"github.com/NaturalSelectionLabs/goapi"
+	"github.com/NaturalSelectionLabs/goapi/lib/openapi"
+	"github.com/ysmood/got"
+)
+
+var errNotFound = errors.New("not found")
+
+var errGone = errors.New("gone")
+
+type resNotFound struct {
+	goapi.StatusNotFound
+	Error openapi.Error
+}
+
+type resConflict struct {
+	goapi.StatusConflict
+	Error openapi.Error
+}
+
+type resGone struct {
+	goapi.StatusNotFound
+	Error openapi.Error
+}
+
+type resBusy struct {
+	goapi.StatusConflict
+	Error struct {
+		Retry int `json:"retry"`
+	}
+}
+
+type resLocked struct {
+	goapi.StatusConflict
+	Error struct {
+		Holder string `json:"holder"`
+	}
+}
+
+func (resGone) Description() string {
+	return "The item is deleted."
+}
+
+func TestMapError(t *testing.T) {
+	g := got.T(t)
+
+	r := goapi.New()
+
+	log := bytes.NewBuffer(nil)
+	r.Router().Logger = slog.New(slog.NewTextHandler(log, nil))
+
+	goapi.MapError(r.Router(), errNotFound, func(err error) resNotFound {
+		return resNotFound{Error: openapi.Error{Code: openapi.CodeNotFound, Message: err.Error()}}
+	})
+
+	goapi.MapErrorAs(r.Router(), func(err *fs.PathError) resConflict {
+		return resConflict{Error: openapi.Error{Message: err.Path}}
+	})
+
+	goapi.MapErrorAs(r.Router(), func(err *fs.PathError) resLocked { return resLocked{} })
+	goapi.MapErrorAs(r.Router(), func(err *fs.PathError) resBusy { return resBusy{} })
+	goapi.MapErrorAs(r.Router(), func(err *fs.PathError) resLocked { return resLocked{} })
+
+	goapi.MapError(r.Router(), errGone, func(err error) resGone {
+		return resGone{Error: openapi.Error{Code: openapi.CodeNotFound, Message: "deleted"}}
+	})
+
+	r.GET("/items/{id}", func(p struct {
+		goapi.InURL
+		ID string
+	}) (resOK, error) {
+		switch p.ID {
+		case "missing":
+			return resOK{}, fmt.Errorf("item %s: %w", p.ID, errNotFound)
+		case "path":
+			return resOK{}, &fs.PathError{Op: "open", Path: "a.txt", Err: fs.ErrExist}
+		case "bad":
+			return resOK{}, errors.New("boom")
+		case "gone":
+			return resOK{}, errGone
+		}
+
+		return resOK{Data: p.ID}, nil
+	})
+
+	tr := g.Serve()
+	tr.Mux.Handle("/", r.Server())
+
+	g.Eq(g.Req("", tr.URL("/items/1")).JSON(), map[string]any{"data": "1"})
+
+	res := g.Req("", tr.URL("/items/missing"))
+	g.Eq(res.StatusCode, http.StatusNotFound)
+	g.Eq(res.JSON(), map[string]any{"error": map[string]any{"code": "not_found", "message": "item missing: not found"}})
+
+	res = g.Req("", tr.URL("/items/path"))
+	g.Eq(res.StatusCode, http.StatusConflict)
+	g.Eq(res.JSON(), map[string]any{"error": map[string]any{"code": "not_found", "message": "a.txt"}})
+
+	res = g.Req("", tr.URL("/items/bad"))
+	g.Eq(res.StatusCode, http.StatusInternalServerError)
+	g.Eq(res.JSON(), map[string]any{"error": map[string]any{"code": "internal_error", "message": "internal server error"}})
+	g.Has(log.String(), `msg="unmapped handler error" method=GET path=/items/bad err=boom`)
+
+	res = g.Req("", tr.URL("/items/gone"))
+	g.Eq(res.StatusCode, http.StatusNotFound)
+	g.Eq(res.JSON(), map[string]any{"error": map[string]any{"code": "not_found", "message": "deleted"}})
+
+	doc := r.OpenAPI().Paths["/items/{id}"][openapi.GET].Responses
+	g.Len(doc, 5)
+	g.Eq(doc[openapi.StatusNotFound].Description, "Not Found\n\nThe item is deleted.")
+	g.Eq(doc[openapi.StatusConflict].Description, "Conflict")
+	g.Len((*doc[openapi.StatusConflict].Content)[openapi.ContentTypeJSON].Schema.AnyOf, 3)
+	g.Eq(doc[openapi.StatusInternalServerError].Description, "The handler returns an unexpected error.")
+
+	g.Eq(g.Panic(func() {
+		r.GET("/x", func() (resOK, string) { return resOK{}, "" })
+	}), "the second return value of handler must be an error")
+}
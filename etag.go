@@ -0,0 +1,136 @@
+package goapi
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/NaturalSelectionLabs/goapi/lib/openapi"
+	"github.com/NaturalSelectionLabs/jschema"
+)
+
+// ETagger can be implemented by a response to enable the automatic ETag for it, check [Router.AutoETag].
+type ETagger interface {
+	// ETag returns the opaque tag without the quotes, such as a version number of the data.
+	// The tag is computed from the response body if it returns an empty string.
+	// It can't contain the double quote, the space, or the control characters, or the request panics.
+	// When the router has more than one encoders, a suffix of the negotiated content type is appended to it,
+	// so each encoding of the data has its own strong ETag.
+	ETag() string
+}
+
+var tETagger = reflect.TypeOf((*ETagger)(nil)).Elem()
+
+// AutoETag returns a copy of the group with the automatic ETag enabled for the operations added to it
+// and its sub groups, check [Router.AutoETag]. The group itself and its existing operations are unchanged.
+func (g *Group) AutoETag() *Group {
+	c := *g
+	c.autoETag = true
+
+	return &c
+}
+
+// useETag returns true if the automatic ETag applies to the response,
+// the HEAD requests handled by [Router.AutoHEAD] use the GET operations.
+func (s *parsedRes) useETag() bool {
+	op := s.operation
+
+	if s.statusCode != http.StatusOK || (op.method != openapi.GET && op.method != openapi.HEAD) {
+		return false
+	}
+
+	if s.hasErr || s.isStream || s.isSSE || s.isItems || !s.hasData {
+		return false
+	}
+
+	return s.isETagger || op.group.autoETag || op.group.router.AutoETag
+}
+
+// customETag returns the quoted tag of the [ETagger] response for the content type, empty if it has none.
+func (s *parsedRes) customETag(res reflect.Value, contentType string) string {
+	if !s.isETagger {
+		return ""
+	}
+
+	tag := res.Interface().(ETagger).ETag()
+	if tag == "" {
+		return ""
+	}
+
+	for _, c := range []byte(tag) {
+		// the etagc of RFC 9110
+		if c == '"' || c <= ' ' || c == 0x7f {
+			panic(fmt.Sprintf("%s invalid ETag %q, it can't contain %q", s.operation.path.path, tag, c))
+		}
+	}
+
+	if len(*s.operation.group.router.encoders.Load()) > 1 {
+		sum := sha256.Sum256([]byte(contentType))
+		tag += "-" + base64.RawURLEncoding.EncodeToString(sum[:6])
+	}
+
+	return `"` + tag + `"`
+}
+
+// bodyETag returns the strong ETag of the encoded body.
+func bodyETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + base64.RawURLEncoding.EncodeToString(sum[:16]) + `"`
+}
+
+// notModified sets the ETag header, it responds 304 and returns true if the ETag matches the If-None-Match
+// header of the request.
+func notModified(w http.ResponseWriter, r *http.Request, etag string) bool {
+	w.Header().Set("ETag", etag)
+
+	if !matchETag(r.Header.Get("If-None-Match"), etag) {
+		return false
+	}
+
+	w.Header().Del("Content-Type")
+	w.Header().Del("Content-Length")
+	w.WriteHeader(http.StatusNotModified)
+
+	return true
+}
+
+// matchETag uses the weak comparison of the If-None-Match list.
+func matchETag(list, etag string) bool {
+	for _, tag := range strings.Split(list, ",") {
+		tag = strings.TrimSpace(tag)
+
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// etagDoc documents the ETag header of the 200 response and the 304 response.
+func etagDoc(list map[openapi.StatusCode]openapi.Response) {
+	etag := openapi.Header{
+		Description: "The strong ETag of the response body.",
+		Required:    true,
+		Schema:      &jschema.Schema{Type: jschema.TypeString},
+	}
+
+	ok := list[openapi.StatusOK]
+
+	headers := openapi.Headers{}
+	for k, v := range ok.Headers {
+		headers[k] = v
+	}
+
+	headers["ETag"] = etag
+	ok.Headers = headers
+	list[openapi.StatusOK] = ok
+
+	list[openapi.StatusNotModified] = openapi.Response{
+		Description: "The ETag matches the If-None-Match header, the response body is omitted.",
+		Headers:     openapi.Headers{"ETag": etag},
+	}
+}
@@ -0,0 +1,142 @@
+package goapi_test
+
+import (
+	"encoding/xml"
+	"io"
+	"net/http"
+	"testing"
+
+	"github.com/NaturalSelectionLabs/goapi"
+	"github.com/NaturalSelectionLabs/goapi/lib/middlewares/calm"
+	"github.com/NaturalSelectionLabs/goapi/lib/openapi"
+	"github.com/ysmood/got"
+)
+
+type resVersioned struct {
+	goapi.StatusOK
+	Data string
+}
+
+func (resVersioned) ETag() string {
+	return "v1"
+}
+
+type resBadTag struct {
+	goapi.StatusOK
+	Data string
+}
+
+func (resBadTag) ETag() string {
+	return `v"1`
+}
+
+func TestAutoETag(t *testing.T) {
+	g := got.T(t)
+
+	r := goapi.New()
+	r.Router().AutoHEAD = true
+	r.Use(&calm.Calm{PrintStack: false})
+
+	cached := r.Group("/cached")
+
+	cached.GET("/plain", func() resOK {
+		return resOK{Data: "a"}
+	})
+
+	cached.AutoETag().GET("/users", func() resOK {
+		return resOK{Data: "a"}
+	})
+
+	r.GET("/plain", func() resOK {
+		return resOK{Data: "a"}
+	})
+
+	r.GET("/versioned", func() resVersioned {
+		return resVersioned{Data: "a"}
+	})
+
+	r.GET("/bad-tag", func() resBadTag {
+		return resBadTag{Data: "a"}
+	})
+
+	tr := g.Serve()
+	tr.Mux.Handle("/", r.Server())
+
+	do := func(method, path, etag string, accept ...string) *http.Response {
+		req, err := http.NewRequest(method, tr.URL(path), nil)
+		g.E(err)
+
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+
+		if len(accept) > 0 {
+			req.Header.Set("Accept", accept[0])
+		}
+
+		res, err := http.DefaultClient.Do(req)
+		g.E(err)
+
+		return res
+	}
+
+	res := do(http.MethodGet, "/cached/users", "")
+	g.Eq(res.StatusCode, http.StatusOK)
+	g.Eq(g.Read(res.Body).String(), `{"data":"a"}`)
+
+	etag := res.Header.Get("ETag")
+	g.Len(etag, 24)
+
+	res = do(http.MethodGet, "/cached/users", `"x", W/`+etag)
+	g.Eq(res.StatusCode, http.StatusNotModified)
+	g.Eq(res.Header.Get("ETag"), etag)
+	g.Eq(res.Header.Get("Content-Type"), "")
+	g.Eq(g.Read(res.Body).String(), "")
+
+	res = do(http.MethodHead, "/cached/users", etag)
+	g.Eq(res.StatusCode, http.StatusNotModified)
+
+	res = do(http.MethodGet, "/cached/users", `"x"`)
+	g.Eq(res.StatusCode, http.StatusOK)
+
+	g.Eq(do(http.MethodGet, "/cached/plain", "").Header.Get("ETag"), "")
+
+	res = do(http.MethodGet, "/plain", "*")
+	g.Eq(res.StatusCode, http.StatusOK)
+	g.Eq(res.Header.Get("ETag"), "")
+
+	res = do(http.MethodGet, "/versioned", "")
+	g.Eq(res.Header.Get("ETag"), `"v1"`)
+
+	res = do(http.MethodGet, "/versioned", `"v1"`)
+	g.Eq(res.StatusCode, http.StatusNotModified)
+
+	res = do(http.MethodGet, "/bad-tag", "")
+	g.Eq(res.StatusCode, http.StatusInternalServerError)
+	g.Has(g.Read(res.Body).String(), `invalid ETag`)
+
+	r.Router().AddEncoder("application/xml", goapi.EncoderFunc(func(w io.Writer, v any) error {
+		return xml.NewEncoder(w).Encode(v)
+	}))
+
+	res = do(http.MethodGet, "/versioned", "")
+	jsonTag := res.Header.Get("ETag")
+	g.Has(jsonTag, `"v1-`)
+	g.Eq(res.Header.Get("Vary"), "Accept")
+
+	res = do(http.MethodGet, "/versioned", "", "application/xml")
+	g.Has(res.Header.Get("ETag"), `"v1-`)
+	g.Neq(res.Header.Get("ETag"), jsonTag)
+
+	res = do(http.MethodGet, "/versioned", jsonTag, "application/xml")
+	g.Eq(res.StatusCode, http.StatusOK)
+
+	res = do(http.MethodGet, "/versioned", jsonTag)
+	g.Eq(res.StatusCode, http.StatusNotModified)
+
+	doc := r.OpenAPI().Paths
+	g.True(doc["/cached/users"][openapi.GET].Responses[openapi.StatusOK].Headers["ETag"].Required)
+	g.Eq(doc["/versioned"][openapi.GET].Responses[openapi.StatusNotModified].Description,
+		"The ETag matches the If-None-Match header, the response body is omitted.")
+	g.Len(doc["/plain"][openapi.GET].Responses, 1)
+}
@@ -14,6 +14,8 @@ import (
 type Group struct {
 	router *Router
 	prefix string
+
+	autoETag bool
 }
 
 // Router returns the router of the group.
@@ -82,14 +84,101 @@ func (g *Group) HEAD(path string, handler OperationHandler) *Operation {
 // If a request matches the path and method, the handler will be called.
 // The router will ignore the trailing slash of the path if a path without trailing slash
 // has not been defined.
+// It panics if the method and path conflict with an existing operation, such as "/users/{id}" and "/users/{name}".
+// It also panics if a schema name of the handler collides with the routers mounted together, check [Group.Mount].
 func (g *Group) Add(method openapi.Method, path string, handler OperationHandler) *Operation {
+	mountMu.Lock()
+	defer mountMu.Unlock()
+
+	op := g.add(method, path, handler)
+
+	if err := g.router.checkSchemas(); err != nil {
+		op.Remove()
+		panic(err)
+	}
+
+	return op
+}
+
+func (g *Group) add(method openapi.Method, path string, handler OperationHandler) *Operation {
+	g.router.mu.Lock()
+	defer g.router.mu.Unlock()
+
 	op := g.newOperation(method, g.prefix+path, handler)
-	g.router.operations = append(g.router.operations, op)
-	g.Use(op)
+	g.router.add(op)
 
 	return op
 }
 
+// Mount h to handle all the requests under the prefix, the prefix will be stripped from the url path
+// before h receives the request, the errors written by the routers still report the original path.
+// If h is a [Router], its operations and the schemas they reference will also be merged into
+// the [Router.OpenAPI] doc of current router, it panics if the schema names of the two routers collide,
+// or h is the current router or contains it.
+// The collision is also checked when an operation is added to either of the routers later.
+// The [Router.AutoOPTIONS] of current router applies to the paths of h that have no OPTIONS operation.
+func (g *Group) Mount(prefix string, h http.Handler) {
+	sub := g.Group(prefix)
+
+	var mounted *mount
+
+	if r, ok := h.(*Router); ok {
+		mounted = g.router.mount(sub.prefix, r)
+		h = g.router.mountHandler(r)
+	}
+
+	strip := http.StripPrefix(sub.prefix, h)
+
+	h = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		strip.ServeHTTP(w, withOriginalPath(r))
+	})
+
+	for _, m := range openapi.MethodValues() {
+		sub.Add(m, "/*", h.ServeHTTP).setMounted(mounted)
+	}
+}
+
+// mountHandler serves the requests of the mounted sub router, the [Router.AutoOPTIONS] of r applies to
+// the paths of sub that have no OPTIONS operation, because the mount handles all the methods.
+func (r *Router) mountHandler(sub *Router) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, rq *http.Request) {
+		if rq.Method == http.MethodOptions && r.AutoOPTIONS {
+			allow := sub.allowedMethods(rq.URL.Path, false)
+
+			if len(allow) > 0 && !containsString(allow, http.MethodOptions) {
+				w.Header().Set("Allow", strings.Join(sub.allowedMethods(rq.URL.Path, true), ", "))
+				w.WriteHeader(http.StatusNoContent)
+
+				return
+			}
+		}
+
+		sub.ServeHTTP(w, rq)
+	})
+}
+
+type originalPathKey struct{}
+
+// withOriginalPath keeps the url path of the request before the mount prefix is stripped,
+// the outermost one is kept for the nested mounts.
+func withOriginalPath(r *http.Request) *http.Request {
+	if _, has := r.Context().Value(originalPathKey{}).(string); has {
+		return r
+	}
+
+	return r.WithContext(context.WithValue(r.Context(), originalPathKey{}, r.URL.Path))
+}
+
+// originalPath returns the url path of the request before any mount prefix is stripped,
+// it's used to report the errors.
+func originalPath(r *http.Request) string {
+	if p, has := r.Context().Value(originalPathKey{}).(string); has {
+		return p
+	}
+
+	return r.URL.Path
+}
+
 // Group creates a sub group of current group.
 func (g *Group) Group(prefix string) *Group {
 	if len(prefix) > 0 && prefix[0] != '/' {
@@ -105,8 +194,9 @@ func (g *Group) Group(prefix string) *Group {
 	}
 
 	return &Group{
-		router: g.router,
-		prefix: g.prefix + prefix,
+		router:   g.router,
+		prefix:   g.prefix + prefix,
+		autoETag: g.autoETag,
 	}
 }
 
@@ -126,10 +216,12 @@ func (g *Group) Shutdown(ctx context.Context) error {
 }
 
 // Use is similar to [Router.Use] but with he group prefix.
+// The middleware only applies to the url path that is under the prefix on the path segment boundary,
+// such as group "/api" applies to "/api" and "/api/users", but not "/apiv2".
 func (g *Group) Use(m middlewares.Middleware) {
 	g.router.Use(middlewares.Func(func(h http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			if strings.HasPrefix(r.URL.Path, g.prefix) {
+			if g.contains(r.URL.Path) {
 				m.Handler(h).ServeHTTP(w, r)
 			} else {
 				h.ServeHTTP(w, r)
@@ -138,6 +230,17 @@ func (g *Group) Use(m middlewares.Middleware) {
 	}))
 }
 
+// contains returns true if the url path is under the prefix of the group.
+func (g *Group) contains(path string) bool {
+	if !strings.HasPrefix(path, g.prefix) {
+		return false
+	}
+
+	rest := path[len(g.prefix):]
+
+	return rest == "" || rest[0] == '/'
+}
+
 // Handler is a shortcut for [Router.Handler].
 func (g *Group) Handler(h http.Handler) http.Handler {
 	return g.router.Handler(h)
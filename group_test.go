@@ -129,3 +129,190 @@ func TestAdd(t *testing.T) {
 
 	g.Eq(g.Req(http.MethodPost, tr.URL("/double"), "3").JSON(), 6)
 }
+
+func TestGroupUseSiblingPrefix(t *testing.T) {
+	g := got.T(t)
+
+	mark := func(name string) middlewares.Middleware {
+		return middlewares.Func(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Add("x-group", name)
+				next.ServeHTTP(w, r)
+			})
+		})
+	}
+
+	tr := setupRouter(g, func(r *goapi.Group) {
+		api := r.Group("/api")
+		api.Use(mark("api"))
+
+		apiV2 := r.Group("/apiv2")
+		apiV2.Use(mark("apiv2"))
+
+		r.Group("/api-internal").Use(mark("api-internal"))
+
+		api.GET("", func() resOK { return resOK{} })
+		api.GET("/users", func() resOK { return resOK{} })
+		apiV2.GET("/users", func() resOK { return resOK{} })
+		r.GET("/api-internal/users", func() resOK { return resOK{} })
+	})
+
+	g.Eq(g.Req("", tr.URL("/api")).Header.Values("x-group"), []string{"api"})
+	g.Eq(g.Req("", tr.URL("/api/users")).Header.Values("x-group"), []string{"api"})
+	g.Eq(g.Req("", tr.URL("/apiv2/users")).Header.Values("x-group"), []string{"apiv2"})
+	g.Eq(g.Req("", tr.URL("/api-internal/users")).Header.Values("x-group"), []string{"api-internal"})
+}
+
+func TestMount(t *testing.T) {
+	g := got.T(t)
+
+	team := goapi.NewRouter()
+	team.Use(middlewares.Func(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("x-team", "ok")
+			next.ServeHTTP(w, r)
+		})
+	}))
+	team.Group("").GET("/users/{id}", func(p struct {
+		goapi.InURL
+		ID string
+	}) resOK {
+		return resOK{Data: p.ID}
+	})
+
+	team.ErrorFormatter = goapi.ProblemDetails{}
+
+	tr := setupRouter(g, func(r *goapi.Group) {
+		r.Router().AutoOPTIONS = true
+
+		r.GET("/users", func() resOK { return resOK{Data: "main"} })
+
+		r.Group("/api").Mount("/team", team)
+
+		r.Mount("/raw", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(r.URL.Path))
+		}))
+	})
+
+	res := g.Req("", tr.URL("/api/team/users/1"))
+	g.Eq(res.JSON(), map[string]any{"data": "1"})
+	g.Eq(res.Header.Get("x-team"), "ok")
+
+	g.Eq(g.Req("", tr.URL("/users")).JSON(), map[string]any{"data": "main"})
+	g.Eq(g.Req("", tr.URL("/users")).Header.Get("x-team"), "")
+	res = g.Req("", tr.URL("/api/team/x"))
+	g.Eq(res.StatusCode, http.StatusNotFound)
+	problem := res.JSON().(map[string]any)
+	g.Eq(problem["instance"], "/api/team/x")
+	g.Eq(problem["detail"], "path not found: GET /api/team/x")
+
+	res = g.Req(http.MethodOptions, tr.URL("/api/team/users/1"))
+	g.Eq(res.StatusCode, http.StatusNoContent)
+	g.Eq(res.Header.Get("Allow"), "GET, OPTIONS")
+	g.Eq(g.Req(http.MethodOptions, tr.URL("/api/team/x")).StatusCode, http.StatusNotFound)
+	g.Eq(g.Req(http.MethodPut, tr.URL("/raw/a/b")).String(), "/a/b")
+	g.Eq(g.Req("", tr.URL("/rawx")).StatusCode, http.StatusNotFound)
+
+	team.Group("").GET("/me", func() resOK { return resOK{Data: "me"} })
+	g.Eq(g.Req("", tr.URL("/api/team/me")).JSON(), map[string]any{"data": "me"})
+}
+
+func TestMountOpenAPI(t *testing.T) {
+	g := got.T(t)
+
+	type User struct {
+		Name string
+	}
+
+	type resUser struct {
+		goapi.StatusOK
+		Data User
+	}
+
+	team := goapi.New()
+	team.GET("/users/{id}", func(p struct {
+		goapi.InURL
+		ID string
+	}) resUser {
+		return resUser{}
+	})
+
+	r := goapi.New()
+	r.GET("/me", func() resUser { return resUser{} })
+	r.Mount("/team", team.Router())
+
+	doc := r.OpenAPI()
+	g.Len(doc.Paths["/me"], 1)
+	g.Len(doc.Paths["/team/users/{id}"], 1)
+	g.Eq(doc.Components.Schemas["User"].Properties["Name"].Type, "string")
+
+	type User2 struct {
+		ID int
+	}
+
+	type resUser2 struct {
+		goapi.StatusOK
+		Data User2
+	}
+
+	other := goapi.New()
+
+	{
+		type User struct {
+			ID int
+		}
+
+		type resUser struct {
+			goapi.StatusOK
+			Data User
+		}
+
+		other.GET("/users", func() resUser { return resUser{} })
+	}
+
+	g.Eq(g.Panic(func() {
+		r.Mount("/other", other.Router())
+	}).(error).Error(), "schema name collision `User`: "+
+		"github.com/NaturalSelectionLabs/goapi_test.User of the router and "+
+		"github.com/NaturalSelectionLabs/goapi_test.User of the router mounted at /other")
+
+	g.Len(r.OpenAPI().Paths, 2)
+
+	other = goapi.New()
+	r.Mount("/other", other.Router())
+
+	other.GET("/users", func() resUser2 { return resUser2{} })
+	g.Len(r.OpenAPI().Paths, 3)
+
+	g.Eq(g.Panic(func() {
+		type User2 struct {
+			Name string
+		}
+
+		type resUser2 struct {
+			goapi.StatusOK
+			Data User2
+		}
+
+		team.GET("/users", func() resUser2 { return resUser2{} })
+	}).(error).Error(), "schema name collision `User2`: "+
+		"github.com/NaturalSelectionLabs/goapi_test.User2 of the router mounted at /team and "+
+		"github.com/NaturalSelectionLabs/goapi_test.User2 of the router mounted at /other")
+
+	g.Len(team.Router().Routes(), 1)
+
+	// the rejected operation doesn't break the later registrations and the doc
+	team.GET("/ok", func() resUser { return resUser{} })
+
+	doc = r.OpenAPI()
+	g.Len(doc.Paths, 4)
+	g.Eq(doc.Components.Schemas["User2"].Properties["ID"].Type, "integer")
+
+	g.Eq(g.Panic(func() {
+		r.Mount("/self", r.Router())
+	}), "can't mount a router into itself or the routers it mounts")
+
+	g.Eq(g.Panic(func() {
+		team.Mount("/parent", r.Router())
+	}), "can't mount a router into itself or the routers it mounts")
+}
@@ -0,0 +1,39 @@
+package bench_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/NaturalSelectionLabs/goapi"
+)
+
+// The latency of routing should stay flat when the number of routes grows.
+func Benchmark_routes(b *testing.B) {
+	for _, n := range []int{10, 100, 1000} {
+		b.Run(fmt.Sprintf("%d", n), func(b *testing.B) {
+			r := goapi.New()
+
+			for i := 0; i < n; i++ {
+				r.GET(fmt.Sprintf("/r%d/users/{id}/posts", i), func(p ParamsGoapi) ResGoapi {
+					return ResGoapi{Data: fmt.Sprintf("%d %s", p.ID, p.Keyword)}
+				})
+			}
+
+			h := r.Server()
+			req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/r%d/users/123/posts?keyword=test", n-1), nil)
+
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				w := httptest.NewRecorder()
+				h.ServeHTTP(w, req)
+
+				if w.Code != http.StatusOK {
+					panic("invalid response")
+				}
+			}
+		})
+	}
+}
@@ -46,8 +46,8 @@ func main() {
 
 	// You can use multiple parameters at the same time to get url values, headers, request context, or request body.
 	// The order of the parameters doesn't matter.
-	g.GET("/users/{id}/posts", func(c context.Context, f ParamsPosts, h ParamsHeader) ResPosts {
-		if h.Cookie != "token=123456" {
+	g.GET("/users/{id}/posts", func(c context.Context, f ParamsPosts, h ParamsCookie) ResPosts {
+		if h.Token != "123456" {
 			return goapi.StatusUnauthorized{}
 		}
 
@@ -4,12 +4,13 @@ import (
 	"github.com/NaturalSelectionLabs/goapi"
 )
 
-// ParamsHeader is the parameters for fetching posts.
-type ParamsHeader struct {
-	// Use goapi.InHeader to get headers values into rest of the fields.
-	goapi.InHeader
+// ParamsCookie is the parameters for fetching posts.
+type ParamsCookie struct {
+	// Use goapi.InCookie to get cookie values into rest of the fields,
+	// goapi.InHeader works the same way for headers.
+	goapi.InCookie
 
-	Cookie string
+	Token string
 }
 
 // ParamsPosts is the parameters for fetching posts.
@@ -40,7 +41,7 @@ type ParamsPagination struct {
 }
 
 // ParamsLogin is the parameters for login.
-// If we don't embed goapi.InURL or goapi.InHeader to the struct,
+// If we don't embed goapi.InURL, goapi.InHeader, goapi.InCookie or goapi.InForm to the struct,
 // It will be treated as the request body json.
 // It should be treated as a common json struct of golang, goapi won't do any special handling for it,
 // such as default field tag won't work.
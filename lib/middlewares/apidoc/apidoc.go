@@ -17,19 +17,14 @@ var swaggerFiles embed.FS
 
 // Install the several endpoints to serve the openapi document for g.
 // If config can be nil if you don't want to modify the generated doc.
+// The doc is generated for each request, so that it reflects the operations added or removed at runtime.
 func Install(g *goapi.Group, config func(doc *openapi.Document) *openapi.Document) {
 	if config == nil {
 		config = func(doc *openapi.Document) *openapi.Document { return doc }
 	}
 
-	var cache *openapi.Document
-
 	g.GET("/openapi.json", func() resOK {
-		if cache == nil {
-			cache = config(g.OpenAPI())
-		}
-
-		return resOK{Data: cache}
+		return resOK{Data: config(g.OpenAPI())}
 	}).OpenAPI(func(doc *openapi.Operation) {
 		doc.Description = "It responds the OpenAPI doc for this service in JSON format."
 	})
@@ -36,7 +36,7 @@ func (c *Calm) Handler(h http.Handler) http.Handler {
 				if c.PrintStack {
 					c.Logger.Error(msg, "stack", string(debug.Stack()))
 				}
-				middlewares.ResponseError(w, http.StatusInternalServerError, &openapi.Error{
+				middlewares.WriteError(w, rq, http.StatusInternalServerError, &openapi.Error{
 					Code:    openapi.CodeInternalError,
 					Message: msg,
 				})
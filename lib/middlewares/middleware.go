@@ -2,6 +2,7 @@
 package middlewares
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 
@@ -28,10 +29,41 @@ var Identity = Func(func(next http.Handler) http.Handler {
 
 // ResponseError writes an error response to w.
 func ResponseError(w http.ResponseWriter, code int, err *openapi.Error) {
-	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	writeJSON(w, code, "application/json; charset=utf-8", openapi.ResponseFormatErr{Error: err})
+}
+
+// ErrorFormatter formats the body of error responses, check [WithErrorFormatter].
+type ErrorFormatter interface {
+	// FormatError returns the content type and the json value of the body of the error response,
+	// err is the error value, such as an [openapi.Error].
+	FormatError(r *http.Request, code int, err any) (contentType string, body any)
+}
+
+type errorFormatterKey struct{}
+
+// WithErrorFormatter returns a shallow copy of r with the formatter for [WriteError].
+func WithErrorFormatter(r *http.Request, f ErrorFormatter) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), errorFormatterKey{}, f))
+}
+
+// WriteError writes an error response to w with the [ErrorFormatter] of r,
+// if r has no formatter it's the same as [ResponseError].
+func WriteError(w http.ResponseWriter, r *http.Request, code int, err any) {
+	f, ok := r.Context().Value(errorFormatterKey{}).(ErrorFormatter)
+	if !ok {
+		writeJSON(w, code, "application/json; charset=utf-8", openapi.ResponseFormatErr{Error: err})
+		return
+	}
+
+	contentType, body := f.FormatError(r, code, err)
+	writeJSON(w, code, contentType, body)
+}
+
+func writeJSON(w http.ResponseWriter, code int, contentType string, body any) {
+	w.Header().Set("Content-Type", contentType)
 	w.WriteHeader(code)
 
-	_ = json.NewEncoder(w).Encode(openapi.ResponseFormatErr{Error: err})
+	_ = json.NewEncoder(w).Encode(body)
 }
 
 // Chain middlewares into one middleware.
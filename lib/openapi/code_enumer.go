@@ -8,11 +8,11 @@ import (
 	"strings"
 )
 
-const _CodeName = "not_foundinvalid_paraminternal_error"
+const _CodeName = "not_foundinvalid_paraminternal_errormethod_not_allowedunsupported_media_typenot_acceptablerequiredpatternminmaxformattypeenum"
 
-var _CodeIndex = [...]uint8{0, 9, 22, 36}
+var _CodeIndex = [...]uint8{0, 9, 22, 36, 54, 76, 90, 98, 105, 108, 111, 117, 121, 125}
 
-const _CodeLowerName = "not_foundinvalid_paraminternal_error"
+const _CodeLowerName = "not_foundinvalid_paraminternal_errormethod_not_allowedunsupported_media_typenot_acceptablerequiredpatternminmaxformattypeenum"
 
 func (i Code) String() string {
 	if i < 0 || i >= Code(len(_CodeIndex)-1) {
@@ -32,23 +32,63 @@ func _CodeNoOp() {
 	_ = x[CodeNotFound-(0)]
 	_ = x[CodeInvalidParam-(1)]
 	_ = x[CodeInternalError-(2)]
+	_ = x[CodeMethodNotAllowed-(3)]
+	_ = x[CodeUnsupportedMediaType-(4)]
+	_ = x[CodeNotAcceptable-(5)]
+	_ = x[CodeRequired-(6)]
+	_ = x[CodePattern-(7)]
+	_ = x[CodeMin-(8)]
+	_ = x[CodeMax-(9)]
+	_ = x[CodeFormat-(10)]
+	_ = x[CodeType-(11)]
+	_ = x[CodeEnum-(12)]
 }
 
-var _CodeValues = []Code{CodeNotFound, CodeInvalidParam, CodeInternalError}
+var _CodeValues = []Code{CodeNotFound, CodeInvalidParam, CodeInternalError, CodeMethodNotAllowed, CodeUnsupportedMediaType, CodeNotAcceptable, CodeRequired, CodePattern, CodeMin, CodeMax, CodeFormat, CodeType, CodeEnum}
 
 var _CodeNameToValueMap = map[string]Code{
-	_CodeName[0:9]:        CodeNotFound,
-	_CodeLowerName[0:9]:   CodeNotFound,
-	_CodeName[9:22]:       CodeInvalidParam,
-	_CodeLowerName[9:22]:  CodeInvalidParam,
-	_CodeName[22:36]:      CodeInternalError,
-	_CodeLowerName[22:36]: CodeInternalError,
+	_CodeName[0:9]:          CodeNotFound,
+	_CodeLowerName[0:9]:     CodeNotFound,
+	_CodeName[9:22]:         CodeInvalidParam,
+	_CodeLowerName[9:22]:    CodeInvalidParam,
+	_CodeName[22:36]:        CodeInternalError,
+	_CodeLowerName[22:36]:   CodeInternalError,
+	_CodeName[36:54]:        CodeMethodNotAllowed,
+	_CodeLowerName[36:54]:   CodeMethodNotAllowed,
+	_CodeName[54:76]:        CodeUnsupportedMediaType,
+	_CodeLowerName[54:76]:   CodeUnsupportedMediaType,
+	_CodeName[76:90]:        CodeNotAcceptable,
+	_CodeLowerName[76:90]:   CodeNotAcceptable,
+	_CodeName[90:98]:        CodeRequired,
+	_CodeLowerName[90:98]:   CodeRequired,
+	_CodeName[98:105]:       CodePattern,
+	_CodeLowerName[98:105]:  CodePattern,
+	_CodeName[105:108]:      CodeMin,
+	_CodeLowerName[105:108]: CodeMin,
+	_CodeName[108:111]:      CodeMax,
+	_CodeLowerName[108:111]: CodeMax,
+	_CodeName[111:117]:      CodeFormat,
+	_CodeLowerName[111:117]: CodeFormat,
+	_CodeName[117:121]:      CodeType,
+	_CodeLowerName[117:121]: CodeType,
+	_CodeName[121:125]:      CodeEnum,
+	_CodeLowerName[121:125]: CodeEnum,
 }
 
 var _CodeNames = []string{
 	_CodeName[0:9],
 	_CodeName[9:22],
 	_CodeName[22:36],
+	_CodeName[36:54],
+	_CodeName[54:76],
+	_CodeName[76:90],
+	_CodeName[90:98],
+	_CodeName[98:105],
+	_CodeName[105:108],
+	_CodeName[108:111],
+	_CodeName[111:117],
+	_CodeName[117:121],
+	_CodeName[121:125],
 }
 
 // CodeString retrieves an enum value from the enum constants string name.
@@ -17,6 +17,26 @@ const (
 	CodeInvalidParam
 	// CodeInternalError ...
 	CodeInternalError
+	// CodeMethodNotAllowed ...
+	CodeMethodNotAllowed
+	// CodeUnsupportedMediaType ...
+	CodeUnsupportedMediaType
+	// CodeNotAcceptable ...
+	CodeNotAcceptable
+	// CodeRequired ...
+	CodeRequired
+	// CodePattern ...
+	CodePattern
+	// CodeMin ...
+	CodeMin
+	// CodeMax ...
+	CodeMax
+	// CodeFormat ...
+	CodeFormat
+	// CodeType ...
+	CodeType
+	// CodeEnum ...
+	CodeEnum
 )
 
 // Method for http request
@@ -54,6 +74,8 @@ const (
 	QUERY
 	// HEADER ...
 	HEADER
+	// COOKIE ...
+	COOKIE
 )
 
 // StatusCode for http response
@@ -36,6 +36,21 @@ type ResponseFormatErr struct {
 
 func (ResponseFormatErr) format() {}
 
+// Problem is the problem details object of RFC 9457 for the "application/problem+json" responses,
+// the fields of [Error] are the extension members.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+
+	Code       *Code               `json:"code,omitempty"`
+	Target     string              `json:"target,omitempty"`
+	Errors     []CommonError[Code] `json:"errors,omitempty"`
+	InnerError any                 `json:"innererror,omitempty"`
+}
+
 // ResponseFormatMeta is the data and meta response format.
 type ResponseFormatMeta struct {
 	Data any `json:"data"`
@@ -8,11 +8,11 @@ import (
 	"strings"
 )
 
-const _ParamInName = "pathqueryheader"
+const _ParamInName = "pathqueryheadercookie"
 
-var _ParamInIndex = [...]uint8{0, 4, 9, 15}
+var _ParamInIndex = [...]uint8{0, 4, 9, 15, 21}
 
-const _ParamInLowerName = "pathqueryheader"
+const _ParamInLowerName = "pathqueryheadercookie"
 
 func (i ParamIn) String() string {
 	if i < 0 || i >= ParamIn(len(_ParamInIndex)-1) {
@@ -32,23 +32,27 @@ func _ParamInNoOp() {
 	_ = x[PATH-(0)]
 	_ = x[QUERY-(1)]
 	_ = x[HEADER-(2)]
+	_ = x[COOKIE-(3)]
 }
 
-var _ParamInValues = []ParamIn{PATH, QUERY, HEADER}
+var _ParamInValues = []ParamIn{PATH, QUERY, HEADER, COOKIE}
 
 var _ParamInNameToValueMap = map[string]ParamIn{
-	_ParamInName[0:4]:       PATH,
-	_ParamInLowerName[0:4]:  PATH,
-	_ParamInName[4:9]:       QUERY,
-	_ParamInLowerName[4:9]:  QUERY,
-	_ParamInName[9:15]:      HEADER,
-	_ParamInLowerName[9:15]: HEADER,
+	_ParamInName[0:4]:        PATH,
+	_ParamInLowerName[0:4]:   PATH,
+	_ParamInName[4:9]:        QUERY,
+	_ParamInLowerName[4:9]:   QUERY,
+	_ParamInName[9:15]:       HEADER,
+	_ParamInLowerName[9:15]:  HEADER,
+	_ParamInName[15:21]:      COOKIE,
+	_ParamInLowerName[15:21]: COOKIE,
 }
 
 var _ParamInNames = []string{
 	_ParamInName[0:4],
 	_ParamInName[4:9],
 	_ParamInName[9:15],
+	_ParamInName[15:21],
 }
 
 // ParamInString retrieves an enum value from the enum constants string name.
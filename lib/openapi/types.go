@@ -53,6 +53,18 @@ type Operation struct {
 	OperationID string                `json:"operationId,omitempty"`
 	Tags        []string              `json:"tags,omitempty"`
 	Extension   Extension             `json:"x-extension,omitempty"`
+
+	// WebSocket describes the messages of a WebSocket operation.
+	WebSocket *WebSocket `json:"x-websocket,omitempty"`
+}
+
+// WebSocket is the "x-websocket" extension of an [Operation], it describes the json messages
+// after the connection is upgraded.
+type WebSocket struct {
+	// Inbound is the schema of the messages from the client.
+	Inbound *jschema.Schema `json:"inbound"`
+	// Outbound is the schema of the messages to the client.
+	Outbound *jschema.Schema `json:"outbound"`
 }
 
 // Parameter represents a parameter in an OpenAPI document.
@@ -92,6 +104,7 @@ type Headers map[string]Header
 // Header represents a header in an OpenAPI document.
 type Header struct {
 	Description string          `json:"description,omitempty"`
+	Required    bool            `json:"required,omitempty"`
 	Schema      *jschema.Schema `json:"schema"`
 }
 
@@ -100,6 +113,16 @@ const (
 	ContentTypeJSON = "application/json"
 	// ContentTypeBin represents the binary http content type.
 	ContentTypeBin = "application/octet-stream"
+	// ContentTypeForm represents the urlencoded form http content type.
+	ContentTypeForm = "application/x-www-form-urlencoded"
+	// ContentTypeMultipart represents the multipart form http content type.
+	ContentTypeMultipart = "multipart/form-data"
+	// ContentTypeNDJSON represents the newline delimited json http content type.
+	ContentTypeNDJSON = "application/x-ndjson"
+	// ContentTypeSSE represents the server-sent events http content type.
+	ContentTypeSSE = "text/event-stream"
+	// ContentTypeProblem represents the RFC 9457 problem details http content type.
+	ContentTypeProblem = "application/problem+json"
 )
 
 // Content represents a content in an OpenAPI document.
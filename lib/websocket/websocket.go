@@ -0,0 +1,520 @@
+// Package websocket is a minimal RFC 6455 implementation based on the standard library.
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1" //nolint: gosec
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// Opcode of a frame.
+type Opcode byte
+
+const (
+	// OpContinuation is the opcode of the continuation frames of a fragmented message.
+	OpContinuation Opcode = 0x0
+	// OpText is the opcode of the text messages.
+	OpText Opcode = 0x1
+	// OpBinary is the opcode of the binary messages.
+	OpBinary Opcode = 0x2
+	// OpClose is the opcode of the close frames.
+	OpClose Opcode = 0x8
+	// OpPing is the opcode of the ping frames.
+	OpPing Opcode = 0x9
+	// OpPong is the opcode of the pong frames.
+	OpPong Opcode = 0xA
+)
+
+// The status codes of the close frames.
+const (
+	CloseNormal          = 1000
+	CloseGoingAway       = 1001
+	CloseProtocolError   = 1002
+	CloseUnsupportedData = 1003
+	CloseNoStatus        = 1005
+	CloseInvalidPayload  = 1007
+	ClosePolicyViolation = 1008
+	CloseTooBig          = 1009
+	CloseInternalError   = 1011
+)
+
+// DefaultMaxMessageSize is the default [Conn.MaxMessageSize].
+const DefaultMaxMessageSize = 1 << 20
+
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// ErrBadHandshake is returned when the opening handshake is invalid.
+var ErrBadHandshake = errors.New("websocket: bad handshake")
+
+// ErrBadOrigin is returned when the origin of the opening handshake is rejected, check [Upgrader.CheckOrigin].
+var ErrBadOrigin = errors.New("websocket: bad origin")
+
+// CloseError is returned by [Conn.ReadMessage] when the peer closes the connection.
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: closed %d %s", e.Code, e.Reason)
+}
+
+// Conn is a WebSocket connection. It's safe to call [Conn.WriteMessage] and [Conn.Close] concurrently,
+// but [Conn.ReadMessage] should only be called by one goroutine.
+type Conn struct {
+	// MaxMessageSize is the max size of a message to read in bytes,
+	// the connection is closed with [CloseTooBig] if a message exceeds it.
+	MaxMessageSize int64
+
+	conn   net.Conn
+	br     *bufio.Reader
+	client bool
+
+	mu     sync.Mutex
+	closed bool
+}
+
+func newConn(conn net.Conn, br *bufio.Reader, client bool) *Conn {
+	return &Conn{
+		MaxMessageSize: DefaultMaxMessageSize,
+		conn:           conn,
+		br:             br,
+		client:         client,
+	}
+}
+
+// IsUpgrade returns true if the request asks to upgrade to the WebSocket protocol.
+func IsUpgrade(r *http.Request) bool {
+	return r.Method == http.MethodGet &&
+		hasToken(r.Header, "Connection", "upgrade") &&
+		hasToken(r.Header, "Upgrade", "websocket")
+}
+
+// Upgrader upgrades the requests to WebSocket connections.
+type Upgrader struct {
+	// CheckOrigin returns false to reject the handshake of r, the default is [SameOrigin].
+	// Browsers don't apply the same-origin policy to WebSocket, so a cross-origin handshake
+	// carries the cookies of the user, only allow the trusted origins.
+	CheckOrigin func(r *http.Request) bool
+}
+
+// Upgrade the request with the default [Upgrader], which only accepts the same-origin handshakes.
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	return (&Upgrader{}).Upgrade(w, r)
+}
+
+// Upgrade the request to a WebSocket connection, it responds 101 Switching Protocols on success.
+// It returns [ErrBadHandshake] without responding if the request is not a valid opening handshake,
+// or [ErrBadOrigin] if the origin is rejected.
+func (u *Upgrader) Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+
+	if !IsUpgrade(r) || r.Header.Get("Sec-WebSocket-Version") != "13" || !validKey(key) {
+		return nil, ErrBadHandshake
+	}
+
+	check := u.CheckOrigin
+	if check == nil {
+		check = SameOrigin
+	}
+
+	if !check(r) {
+		return nil, ErrBadOrigin
+	}
+
+	conn, brw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("websocket: failed to hijack the connection: %w", err)
+	}
+
+	_, _ = brw.WriteString("HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n")
+
+	if err := brw.Flush(); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+
+	return newConn(conn, brw.Reader, false), nil
+}
+
+// SameOrigin returns true if the request has no Origin header, or the host of the Origin is the Host of the request.
+func SameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+// Dial opens a WebSocket connection to the "ws" or "wss" url, the header is added to the opening handshake.
+// The response of the handshake is returned even if the handshake fails.
+func Dial(ctx context.Context, rawURL string, header http.Header) (*Conn, *http.Response, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	host := u.Host
+	if u.Port() == "" {
+		port := "80"
+		if u.Scheme == "wss" {
+			port = "443"
+		}
+
+		host = net.JoinHostPort(u.Hostname(), port)
+	}
+
+	var conn net.Conn
+
+	switch u.Scheme {
+	case "ws":
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", host)
+	case "wss":
+		conn, err = (&tls.Dialer{}).DialContext(ctx, "tcp", host)
+	default:
+		return nil, nil, fmt.Errorf("websocket: unsupported url scheme: %s", u.Scheme)
+	}
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	c, res, err := handshake(conn, u, header)
+	if err != nil {
+		_ = conn.Close()
+	}
+
+	return c, res, err
+}
+
+func handshake(conn net.Conn, u *url.URL, header http.Header) (*Conn, *http.Response, error) {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	key := base64.StdEncoding.EncodeToString(b)
+
+	u = &url.URL{Scheme: "http", Host: u.Host, Path: u.Path, RawPath: u.RawPath, RawQuery: u.RawQuery}
+
+	req := &http.Request{
+		Method:     http.MethodGet,
+		URL:        u,
+		Host:       u.Host,
+		Header:     http.Header{},
+		Proto:      "HTTP/1.1",
+		ProtoMajor: 1,
+		ProtoMinor: 1,
+	}
+
+	for k, vs := range header {
+		req.Header[k] = vs
+	}
+
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Sec-WebSocket-Key", key)
+	req.Header.Set("Sec-WebSocket-Version", "13")
+
+	if err := req.Write(conn); err != nil {
+		return nil, nil, err
+	}
+
+	br := bufio.NewReader(conn)
+
+	res, err := http.ReadResponse(br, req)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if res.StatusCode != http.StatusSwitchingProtocols || res.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		return nil, res, ErrBadHandshake
+	}
+
+	return newConn(conn, br, true), res, nil
+}
+
+// ReadMessage reads the next text or binary message, the fragmented frames are joined.
+// The ping frames are replied automatically, and a close frame is replied and returned as a [*CloseError].
+func (c *Conn) ReadMessage() (Opcode, []byte, error) {
+	var op Opcode
+
+	msg := []byte{}
+
+	for {
+		fin, frameOp, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch frameOp {
+		case OpPing:
+			if err := c.writeFrame(OpPong, payload); err != nil {
+				return 0, nil, err
+			}
+
+			continue
+
+		case OpPong:
+			continue
+
+		case OpClose:
+			return 0, nil, c.onClose(payload)
+
+		case OpText, OpBinary:
+			if op != 0 {
+				return 0, nil, c.fail(CloseProtocolError, "expect a continuation frame")
+			}
+
+			op = frameOp
+
+		case OpContinuation:
+			if op == 0 {
+				return 0, nil, c.fail(CloseProtocolError, "unexpected continuation frame")
+			}
+
+		default:
+			return 0, nil, c.fail(CloseProtocolError, fmt.Sprintf("unknown opcode %d", frameOp))
+		}
+
+		if int64(len(msg)+len(payload)) > c.MaxMessageSize {
+			return 0, nil, c.fail(CloseTooBig, "message too big")
+		}
+
+		msg = append(msg, payload...)
+
+		if fin {
+			if op == OpText && !utf8.Valid(msg) {
+				return 0, nil, c.fail(CloseInvalidPayload, "invalid utf-8 text")
+			}
+
+			return op, msg, nil
+		}
+	}
+}
+
+// WriteMessage writes a text or binary message as a single frame.
+func (c *Conn) WriteMessage(op Opcode, data []byte) error {
+	return c.writeFrame(op, data)
+}
+
+// Close sends the close frame with the code and reason, then closes the underlying connection.
+func (c *Conn) Close(code int, reason string) error {
+	_ = c.writeFrame(OpClose, closePayload(code, reason))
+
+	return c.close()
+}
+
+func (c *Conn) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil
+	}
+
+	c.closed = true
+
+	return c.conn.Close()
+}
+
+// onClose replies the close frame of the peer.
+func (c *Conn) onClose(payload []byte) error {
+	e := &CloseError{Code: CloseNoStatus}
+
+	if len(payload) >= 2 {
+		e.Code = int(binary.BigEndian.Uint16(payload))
+		e.Reason = string(payload[2:])
+	}
+
+	_ = c.writeFrame(OpClose, closePayload(e.Code, ""))
+	_ = c.close()
+
+	return e
+}
+
+// fail closes the connection because of the error of the peer.
+func (c *Conn) fail(code int, reason string) error {
+	_ = c.Close(code, reason)
+
+	return fmt.Errorf("websocket: %s", reason)
+}
+
+func (c *Conn) readFrame() (fin bool, op Opcode, payload []byte, err error) { //nolint: nonamedreturns
+	var head [2]byte
+
+	if _, err = io.ReadFull(c.br, head[:]); err != nil {
+		return
+	}
+
+	fin = head[0]&0x80 != 0
+	op = Opcode(head[0] & 0x0f)
+	masked := head[1]&0x80 != 0
+	size := uint64(head[1] & 0x7f)
+
+	if head[0]&0x70 != 0 {
+		err = c.fail(CloseProtocolError, "reserved bits are set")
+		return
+	}
+
+	if masked == c.client {
+		err = c.fail(CloseProtocolError, "invalid frame mask")
+		return
+	}
+
+	if op >= OpClose && (!fin || size > 125) {
+		err = c.fail(CloseProtocolError, "invalid control frame")
+		return
+	}
+
+	switch size {
+	case 126:
+		var b [2]byte
+		if _, err = io.ReadFull(c.br, b[:]); err != nil {
+			return
+		}
+
+		size = uint64(binary.BigEndian.Uint16(b[:]))
+
+	case 127:
+		var b [8]byte
+		if _, err = io.ReadFull(c.br, b[:]); err != nil {
+			return
+		}
+
+		size = binary.BigEndian.Uint64(b[:])
+	}
+
+	if size > uint64(c.MaxMessageSize) {
+		err = c.fail(CloseTooBig, "message too big")
+		return
+	}
+
+	var mask [4]byte
+
+	if masked {
+		if _, err = io.ReadFull(c.br, mask[:]); err != nil {
+			return
+		}
+	}
+
+	payload = make([]byte, size)
+	if _, err = io.ReadFull(c.br, payload); err != nil {
+		return
+	}
+
+	if masked {
+		maskBytes(mask, payload)
+	}
+
+	return
+}
+
+func (c *Conn) writeFrame(op Opcode, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return net.ErrClosed
+	}
+
+	frame := []byte{0x80 | byte(op)}
+
+	var maskBit byte
+	if c.client {
+		maskBit = 0x80
+	}
+
+	switch size := len(payload); {
+	case size <= 125:
+		frame = append(frame, maskBit|byte(size))
+	case size <= 0xffff:
+		frame = append(frame, maskBit|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(size))
+	default:
+		frame = append(frame, maskBit|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(size))
+	}
+
+	if c.client {
+		var mask [4]byte
+		_, _ = rand.Read(mask[:])
+		frame = append(frame, mask[:]...)
+
+		data := append([]byte{}, payload...)
+		maskBytes(mask, data)
+		payload = data
+	}
+
+	_, err := c.conn.Write(append(frame, payload...))
+
+	return err
+}
+
+func maskBytes(mask [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= mask[i%4]
+	}
+}
+
+func closePayload(code int, reason string) []byte {
+	if code == CloseNoStatus {
+		return nil
+	}
+
+	// the payload of a control frame can't exceed 125 bytes, the reason is cut on a rune boundary
+	if len(reason) > 123 {
+		end := 123
+		for end > 0 && !utf8.RuneStart(reason[end]) {
+			end--
+		}
+
+		reason = reason[:end]
+	}
+
+	return append(binary.BigEndian.AppendUint16(nil, uint16(code)), reason...)
+}
+
+func acceptKey(key string) string {
+	h := sha1.New() //nolint: gosec
+	h.Write([]byte(key + acceptGUID))
+
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func validKey(key string) bool {
+	b, err := base64.StdEncoding.DecodeString(key)
+	return err == nil && len(b) == 16
+}
+
+// hasToken returns true if the comma separated header contains the token, case-insensitively.
+func hasToken(h http.Header, name, token string) bool {
+	for _, v := range h.Values(name) {
+		for _, t := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(t), token) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
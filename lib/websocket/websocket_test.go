@@ -0,0 +1,120 @@
+package websocket_test
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/NaturalSelectionLabs/goapi/lib/websocket"
+	"github.com/ysmood/got"
+)
+
+func TestEcho(t *testing.T) {
+	g := got.T(t)
+
+	tr := g.Serve()
+	tr.Mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Upgrade(w, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		conn.MaxMessageSize = 100000
+
+		for {
+			op, msg, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			switch string(msg) {
+			case "close":
+				_ = conn.Close(websocket.ClosePolicyViolation, "bye")
+				return
+			case "close-long":
+				_ = conn.Close(websocket.ClosePolicyViolation, "a"+strings.Repeat("é", 100))
+				return
+			}
+
+			_ = conn.WriteMessage(op, msg)
+		}
+	})
+
+	tr.Mux.HandleFunc("/any-origin", func(w http.ResponseWriter, r *http.Request) {
+		u := &websocket.Upgrader{CheckOrigin: func(*http.Request) bool { return true }}
+
+		conn, err := u.Upgrade(w, r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
+
+		_ = conn.Close(websocket.CloseNormal, "")
+	})
+
+	url := "ws" + strings.TrimPrefix(tr.URL(), "http")
+
+	conn, res, err := websocket.Dial(g.Context(), url, http.Header{"X-Test": {"ok"}})
+	g.E(err)
+	g.Eq(res.StatusCode, http.StatusSwitchingProtocols)
+
+	for _, size := range []int{0, 10, 200, 70000} {
+		data := bytes.Repeat([]byte("a"), size)
+
+		g.E(conn.WriteMessage(websocket.OpBinary, data))
+
+		op, msg, err := conn.ReadMessage()
+		g.E(err)
+		g.Eq(op, websocket.OpBinary)
+		g.Eq(msg, data)
+	}
+
+	g.E(conn.WriteMessage(websocket.OpText, []byte("close")))
+
+	_, _, err = conn.ReadMessage()
+	g.Eq(err, &websocket.CloseError{Code: websocket.ClosePolicyViolation, Reason: "bye"})
+
+	conn, _, err = websocket.Dial(g.Context(), url, nil)
+	g.E(err)
+	g.E(conn.WriteMessage(websocket.OpText, []byte("close-long")))
+
+	_, _, err = conn.ReadMessage()
+	g.Eq(err, &websocket.CloseError{Code: websocket.ClosePolicyViolation, Reason: "a" + strings.Repeat("é", 61)})
+
+	conn, _, err = websocket.Dial(g.Context(), url, nil)
+	g.E(err)
+	g.E(conn.WriteMessage(websocket.OpBinary, make([]byte, 100001)))
+
+	_, _, err = conn.ReadMessage()
+	g.Eq(err, &websocket.CloseError{Code: websocket.CloseTooBig, Reason: "message too big"})
+
+	conn, _, err = websocket.Dial(g.Context(), url, http.Header{"Origin": {tr.URL()}})
+	g.E(err)
+	g.E(conn.Close(websocket.CloseNormal, ""))
+
+	_, res, err = websocket.Dial(g.Context(), url, http.Header{"Origin": {"http://example.com"}})
+	g.Err(err)
+	g.Eq(res.StatusCode, http.StatusBadRequest)
+	g.Has(g.Read(res.Body).String(), websocket.ErrBadOrigin.Error())
+
+	conn, _, err = websocket.Dial(g.Context(), url+"/any-origin", http.Header{"Origin": {"http://example.com"}})
+	g.E(err)
+	g.E(conn.Close(websocket.CloseNormal, ""))
+
+	_, res, err = websocket.Dial(g.Context(), strings.Replace(url, "ws", "wss", 1), nil)
+	g.Err(err)
+	g.Nil(res)
+
+	req, err := http.NewRequest(http.MethodGet, tr.URL(), nil)
+	g.E(err)
+	req.Header.Set("Connection", "keep-alive, Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	g.True(websocket.IsUpgrade(req))
+
+	res, err = http.DefaultClient.Do(req)
+	g.E(err)
+	g.Eq(res.StatusCode, http.StatusBadRequest)
+	g.Has(g.Read(res.Body).String(), websocket.ErrBadHandshake.Error())
+}
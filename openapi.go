@@ -1,12 +1,18 @@
 package goapi
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"reflect"
+	"regexp"
+	"sort"
 	"strconv"
+	"strings"
 
 	ff "github.com/NaturalSelectionLabs/goapi/lib/flat-fields"
+	"github.com/NaturalSelectionLabs/goapi/lib/middlewares"
 	"github.com/NaturalSelectionLabs/goapi/lib/openapi"
 	"github.com/NaturalSelectionLabs/jschema"
 	"github.com/iancoleman/strcase"
@@ -47,41 +53,259 @@ type ContentTyper interface {
 
 var tContentTyper = reflect.TypeOf((*ContentTyper)(nil)).Elem()
 
+// OpenAPIMiddleware is a middleware that can modify the OpenAPI doc of the operation it's used by,
+// such as adding security requirements or extra responses. Check [Operation.Use].
+type OpenAPIMiddleware interface {
+	middlewares.Middleware
+	OpenAPI(doc *openapi.Operation)
+}
+
 // OpenAPI is a shortcut for [Router.OpenAPI].
 func (g *Group) OpenAPI() *openapi.Document {
 	return g.router.OpenAPI()
 }
 
-// OpenAPI returns the OpenAPI doc of the router.
+// OpenAPI returns the OpenAPI doc of the router, disabled operations are excluded.
 // You can use [json.Marshal] to convert it to a JSON string.
+// The config functions of the operations are called without the lock of the router,
+// so they can register operations.
 func (r *Router) OpenAPI() *openapi.Document {
+	docs, mounts, schemas := r.handlerDocs()
+
 	doc := &openapi.Document{
 		Paths: map[string]openapi.Path{},
 	}
 
-	for _, op := range r.operations {
-		if op.override != nil {
+	for _, d := range docs {
+		if _, has := doc.Paths[d.path]; !has {
+			doc.Paths[d.path] = openapi.Path{}
+		}
+
+		doc.Paths[d.path][d.method] = d.configure()
+	}
+
+	if r.OpenAPIAutoMethods {
+		for _, p := range doc.Paths {
+			r.autoMethodsDoc(p)
+		}
+	}
+
+	doc.Components.Schemas = schemas
+
+	// Only the schemas referenced by the paths are merged, the ones left by a rejected operation are ignored.
+	set := schemaSet{}
+	_ = set.merge("", reachableSchemas(schemaRefs(doc.Paths), schemas))
+
+	for _, m := range mounts {
+		sub := m.router.OpenAPI()
+		used := reachableSchemas(schemaRefs(sub.Paths), sub.Components.Schemas)
+
+		for p, item := range sub.Paths {
+			doc.Paths[m.prefix+p] = item
+		}
+
+		// The collisions are already checked at the registration,
+		// it only happens when a schema is defined after it, such as by a custom [ErrorFormatter].
+		if err := set.merge(m.prefix, used); err != nil {
+			panic(err)
+		}
+
+		for name, scm := range used {
+			doc.Components.Schemas[name] = scm
+		}
+	}
+
+	return doc
+}
+
+// autoMethodsDoc adds the docs of the operations enabled by [Router.AutoHEAD] and [Router.AutoOPTIONS].
+func (r *Router) autoMethodsDoc(p openapi.Path) {
+	if get, has := p[openapi.GET]; has && r.AutoHEAD {
+		if _, has := p[openapi.HEAD]; !has {
+			head := get
+			head.OperationID = ""
+			head.Responses = map[openapi.StatusCode]openapi.Response{}
+
+			for code, res := range get.Responses {
+				res.Content = nil
+				head.Responses[code] = res
+			}
+
+			p[openapi.HEAD] = head
+		}
+	}
+
+	if _, has := p[openapi.OPTIONS]; !has && r.AutoOPTIONS {
+		params := []openapi.Parameter{}
+
+		for _, m := range openapi.MethodValues() {
+			if op, has := p[m]; has {
+				for _, param := range op.Parameters {
+					if param.In == openapi.PATH {
+						params = append(params, param)
+					}
+				}
+
+				break
+			}
+		}
+
+		p[openapi.OPTIONS] = openapi.Operation{
+			Parameters: params,
+			Responses: map[openapi.StatusCode]openapi.Response{
+				openapi.StatusNoContent: {
+					Description: "It lists the allowed methods of the path in the Allow header.",
+					Headers: openapi.Headers{
+						"Allow": {Schema: &jschema.Schema{Type: jschema.TypeString}},
+					},
+				},
+			},
+		}
+	}
+}
+
+// schemaSet is the schemas of the routers mounted together, the key is the schema name.
+type schemaSet map[string]schemaOrigin
+
+// schemaOrigin is a schema and the mount prefix of the router that defines it.
+type schemaOrigin struct {
+	json        []byte
+	description string
+	prefix      string
+}
+
+// merge the schemas of the router mounted at prefix, it returns error if a name refers to different schemas.
+func (set schemaSet) merge(prefix string, schemas map[string]*jschema.Schema) error {
+	names := []string{}
+	for name := range schemas {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		b, _ := json.Marshal(schemas[name])
+
+		if o, has := set[name]; has {
+			if !bytes.Equal(o.json, b) {
+				return fmt.Errorf("schema name collision `%s`: %s of %s and %s of %s", name,
+					o.description, routerName(o.prefix), schemas[name].Description, routerName(prefix))
+			}
+
 			continue
 		}
 
-		if _, has := doc.Paths[op.path.path]; !has {
-			doc.Paths[op.path.path] = openapi.Path{}
+		set[name] = schemaOrigin{json: b, description: schemas[name].Description, prefix: prefix}
+	}
+
+	return nil
+}
+
+var regSchemaRef = regexp.MustCompile(`"\$ref":"[^"]*/([^"/]+)"`)
+
+// schemaRefs returns the names of the schemas referenced by v.
+func schemaRefs(v any) []string {
+	b, _ := json.Marshal(v)
+
+	list := []string{}
+	for _, m := range regSchemaRef.FindAllSubmatch(b, -1) {
+		list = append(list, string(m[1]))
+	}
+
+	return list
+}
+
+// reachableSchemas returns the schemas of all that are referenced by the names directly or indirectly.
+func reachableSchemas(names []string, all map[string]*jschema.Schema) map[string]*jschema.Schema {
+	used := map[string]*jschema.Schema{}
+
+	for len(names) > 0 {
+		name := names[len(names)-1]
+		names = names[:len(names)-1]
+
+		scm, has := all[name]
+		if _, done := used[name]; done || !has {
+			continue
 		}
 
-		doc.Paths[op.path.path][op.method] = operationDoc(r.Schemas, op)
+		used[name] = scm
+		names = append(names, schemaRefs(scm)...)
 	}
 
-	doc.Components.Schemas = r.Schemas.JSON()
+	return used
+}
 
-	return doc
+func routerName(prefix string) string {
+	if prefix == "" {
+		return "the router"
+	}
+
+	return "the router mounted at " + prefix
 }
 
 // OpenAPI sets the config function to modify the generated OpenAPI doc.
+// It's safe to call it while the router is serving.
 func (op *Operation) OpenAPI(config ConfigOpenAPI) {
+	r := op.group.router
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	op.configOpenAPI = config
 }
 
-func operationDoc(s jschema.Schemas, op *Operation) openapi.Operation {
+// operationDoc is the doc of an operation collected under the lock of the router,
+// with the snapshot of the hooks to modify it.
+type operationDoc struct {
+	path        string
+	method      openapi.Method
+	doc         openapi.Operation
+	middlewares []middlewares.Middleware
+	config      ConfigOpenAPI
+}
+
+// handlerDocs returns the docs of the enabled operations, the mounts, and the schemas of the router.
+func (r *Router) handlerDocs() ([]*operationDoc, []*mount, map[string]*jschema.Schema) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list := []*operationDoc{}
+
+	for _, op := range r.operations {
+		if op.override != nil || op.disabled.Load() {
+			continue
+		}
+
+		list = append(list, &operationDoc{
+			path:        op.path.doc(),
+			method:      op.method,
+			doc:         handlerDoc(r.Schemas, op),
+			middlewares: op.chain(),
+			config:      op.configOpenAPI,
+		})
+	}
+
+	return list, append([]*mount{}, r.mounts...), r.Schemas.JSON()
+}
+
+// configure applies the [OpenAPIMiddleware] and the config function to the doc.
+func (d *operationDoc) configure() openapi.Operation {
+	for _, m := range d.middlewares {
+		if m, ok := m.(OpenAPIMiddleware); ok {
+			m.OpenAPI(&d.doc)
+		}
+	}
+
+	if d.config != nil {
+		d.config(&d.doc)
+	}
+
+	return d.doc
+}
+
+// handlerDoc returns the doc generated from the handler signature,
+// without the changes of the middlewares and the config function.
+func handlerDoc(s jschema.Schemas, op *Operation) openapi.Operation {
 	doc := openapi.Operation{
 		OperationID: op.name,
 		Parameters:  []openapi.Parameter{},
@@ -93,29 +317,48 @@ func operationDoc(s jschema.Schemas, op *Operation) openapi.Operation {
 
 		switch p.in {
 		case inHeader:
-			params = append(params, headerParamDoc(s, p)...)
+			params = append(params, fieldParamDoc(s, p, openapi.HEADER)...)
+
+		case inCookie:
+			params = append(params, fieldParamDoc(s, p, openapi.COOKIE)...)
 
 		case inURL:
 			params = append(params, urlParamDoc(s, p)...)
 
-		case inBody:
+		case inForm:
+			doc.RequestBody = &openapi.RequestBody{
+				Content: &openapi.Content{
+					openapi.ContentTypeForm: &openapi.Schema{
+						Schema: formSchema(s, p),
+					},
+				},
+				Required: true,
+			}
+
+		case inMultipart:
 			doc.RequestBody = &openapi.RequestBody{
 				Content: &openapi.Content{
-					getContentType(p.param, openapi.ContentTypeJSON): &openapi.Schema{
-						Schema: s.DefineT(p.param),
+					openapi.ContentTypeMultipart: &openapi.Schema{
+						Schema: multipartSchema(s, p),
 					},
 				},
 				Required: true,
 			}
+
+		case inBody:
+			doc.RequestBody = &openapi.RequestBody{
+				Content:  op.group.router.bodyContent(s, p.param),
+				Required: true,
+			}
 		}
 
 		doc.Parameters = append(doc.Parameters, params...)
 	}
 
-	doc.Responses = resDoc(s, op)
-
-	if op.configOpenAPI != nil {
-		op.configOpenAPI(&doc)
+	if op.isWS() {
+		wsDoc(s, op, &doc)
+	} else {
+		doc.Responses = resDoc(s, op)
 	}
 
 	return doc
@@ -136,6 +379,10 @@ func urlParamDoc(s jschema.Schemas, p *parsedParam) []openapi.Parameter {
 		schema.Description = ""
 		examples := map[string]openapi.Example{}
 
+		if f.pathParam != nil && schema.Type == jschema.TypeString {
+			schema.Pattern = f.pathParam.pattern()
+		}
+
 		if len(schema.Examples) > 0 {
 			for i, e := range schema.Examples {
 				b, _ := json.Marshal(e)
@@ -161,7 +408,7 @@ func urlParamDoc(s jschema.Schemas, p *parsedParam) []openapi.Parameter {
 	return arr
 }
 
-func headerParamDoc(s jschema.Schemas, p *parsedParam) []openapi.Parameter {
+func fieldParamDoc(s jschema.Schemas, p *parsedParam, in openapi.ParamIn) []openapi.Parameter {
 	arr := []openapi.Parameter{}
 
 	for _, f := range p.fields {
@@ -171,7 +418,7 @@ func headerParamDoc(s jschema.Schemas, p *parsedParam) []openapi.Parameter {
 
 		arr = append(arr, openapi.Parameter{
 			Name:        f.name,
-			In:          openapi.HEADER,
+			In:          in,
 			Schema:      schema,
 			Description: desc,
 			Required:    f.required,
@@ -181,6 +428,24 @@ func headerParamDoc(s jschema.Schemas, p *parsedParam) []openapi.Parameter {
 	return arr
 }
 
+func formSchema(s jschema.Schemas, p *parsedParam) *jschema.Schema {
+	scm := &jschema.Schema{
+		Type:                 jschema.TypeObject,
+		AdditionalProperties: ptr(false),
+		Properties:           jschema.Properties{},
+	}
+
+	for _, f := range p.fields {
+		scm.Properties[f.name] = fieldSchema(s, f.flatField.Field)
+
+		if f.required {
+			scm.Required = append(scm.Required, f.name)
+		}
+	}
+
+	return scm
+}
+
 func fieldSchema(s jschema.Schemas, f reflect.StructField) *jschema.Schema {
 	if f.Type.Kind() == reflect.Ptr {
 		f.Type = f.Type.Elem()
@@ -195,7 +460,7 @@ func fieldSchema(s jschema.Schemas, f reflect.StructField) *jschema.Schema {
 func resDoc(s jschema.Schemas, op *Operation) map[openapi.StatusCode]openapi.Response {
 	list := map[openapi.StatusCode]openapi.Response{}
 
-	add := func(t reflect.Type) {
+	add := func(list map[openapi.StatusCode]openapi.Response, t reflect.Type) {
 		parsedRes := op.parseResponse(t)
 
 		var content *openapi.Content
@@ -209,36 +474,21 @@ func resDoc(s jschema.Schemas, op *Operation) map[openapi.StatusCode]openapi.Res
 					},
 				},
 			}
-		} else if parsedRes.isDirect {
+		} else if parsedRes.isSSE {
 			content = &openapi.Content{
-				getContentType(t, openapi.ContentTypeJSON): &openapi.Schema{
-					Schema: s.DefineT(parsedRes.data),
+				openapi.ContentTypeSSE: &openapi.Schema{
+					Schema: sseSchema(s, parsedRes.data),
 				},
 			}
+		} else if parsedRes.isItems {
+			content = op.group.router.streamContent(s, parsedRes.data)
+		} else if parsedRes.isDirect {
+			content = op.group.router.resContent(t, s.DefineT(parsedRes.data))
+		} else if parsedRes.hasErr && op.group.router.ErrorFormatter != nil {
+			content = op.group.router.errorContent(s, parsedRes.err)
 		} else if parsedRes.hasData || parsedRes.hasErr {
-			scm := &jschema.Schema{
-				Type:                 jschema.TypeObject,
-				AdditionalProperties: ptr(false),
-				Properties:           jschema.Properties{},
-			}
-
-			if parsedRes.hasErr { //nolint: gocritic
-				scm.Properties["error"] = s.DefineT(parsedRes.err)
-				scm.Required = []string{"error"}
-			} else if parsedRes.hasMeta {
-				scm.Properties["data"] = s.DefineT(parsedRes.data)
-				scm.Properties["meta"] = s.DefineT(parsedRes.meta)
-				scm.Required = []string{"data", "meta"}
-			} else {
-				scm.Properties["data"] = s.DefineT(parsedRes.data)
-				scm.Required = []string{"data"}
-			}
-
-			content = &openapi.Content{
-				getContentType(t, openapi.ContentTypeJSON): &openapi.Schema{
-					Schema: scm,
-				},
-			}
+			scm := op.group.router.envelope().Schema(parsedRes.envelopeSchemas(s))
+			content = op.group.router.resContent(t, scm)
 		}
 
 		code := openapi.StatusCode(parsedRes.statusCode)
@@ -250,19 +500,168 @@ func resDoc(s jschema.Schemas, op *Operation) map[openapi.StatusCode]openapi.Res
 		}
 
 		list[code] = res
+
+		if parsedRes.isStream && code == openapi.StatusOK {
+			rangeDoc(list, res)
+		}
+
+		if parsedRes.useETag() {
+			etagDoc(list)
+		}
 	}
 
 	if it, has := Interfaces[vary.ID(op.tRes)]; has {
 		for _, t := range it.Implementations {
-			add(t)
+			add(list, t)
 		}
 	} else {
-		add(op.tRes)
+		add(list, op.tRes)
+	}
+
+	if op.returnsErr {
+		for _, t := range op.group.router.errorResTypes() {
+			mapped := map[openapi.StatusCode]openapi.Response{}
+			add(mapped, t)
+
+			for code, res := range mapped {
+				mergeResponse(list, code, res)
+			}
+		}
+
+		if !hasStatus(list, http.StatusInternalServerError) {
+			list[openapi.StatusInternalServerError] = op.group.router.errorResDoc(s, "The handler returns an unexpected error.")
+		}
+	}
+
+	if _, has := list[openapi.StatusBadRequest]; !has && op.hasParams() {
+		list[openapi.StatusBadRequest] = op.group.router.errorResDoc(s, "The request parameters are invalid, "+
+			"each invalid parameter is listed in the error details.")
 	}
 
 	return list
 }
 
+func (s *parsedRes) envelopeSchemas(schemas jschema.Schemas) EnvelopeSchemas {
+	if s.hasErr {
+		return EnvelopeSchemas{Error: schemas.DefineT(s.err)}
+	}
+
+	p := EnvelopeSchemas{Data: schemas.DefineT(s.data)}
+
+	if s.hasMeta {
+		p.Meta = schemas.DefineT(s.meta)
+	}
+
+	return p
+}
+
+// rangeDoc documents the range requests of the 200 [DataStream] response res.
+func rangeDoc(list map[openapi.StatusCode]openapi.Response, res openapi.Response) {
+	str := &jschema.Schema{Type: jschema.TypeString}
+
+	headers := func(extra openapi.Headers) openapi.Headers {
+		h := openapi.Headers{}
+
+		for k, v := range res.Headers {
+			h[k] = v
+		}
+
+		for k, v := range extra {
+			h[k] = v
+		}
+
+		return h
+	}
+
+	ok := res
+	ok.Headers = headers(openapi.Headers{
+		"Accept-Ranges": {Description: "It's bytes if the data supports the range requests.", Schema: str},
+	})
+	list[openapi.StatusOK] = ok
+
+	partial := res
+	partial.Description = "The requested range of the data, if the data supports the range requests."
+	partial.Headers = headers(openapi.Headers{
+		"Accept-Ranges": {Schema: str},
+		"Content-Range": {Required: true, Schema: str},
+	})
+	list[openapi.StatusPartialContent] = partial
+}
+
+// mergeResponse adds res to the list, if the status code already exists,
+// the descriptions are joined and the different schemas of a content type become anyOf.
+func mergeResponse(list map[openapi.StatusCode]openapi.Response, code openapi.StatusCode, res openapi.Response) {
+	prev, has := list[code]
+	if !has {
+		list[code] = res
+		return
+	}
+
+	if !containsString(strings.Split(prev.Description, "\n\n"), res.Description) {
+		prev.Description += "\n\n" + res.Description
+	}
+
+	if res.Content != nil {
+		content := openapi.Content{}
+
+		if prev.Content != nil {
+			for typ, scm := range *prev.Content {
+				content[typ] = scm
+			}
+		}
+
+		for typ, scm := range *res.Content {
+			if p, has := content[typ]; !has {
+				content[typ] = scm
+			} else if !reflect.DeepEqual(p.Schema, scm.Schema) {
+				content[typ] = &openapi.Schema{Schema: &jschema.Schema{AnyOf: anyOf(p.Schema, scm.Schema)}}
+			}
+		}
+
+		prev.Content = &content
+	}
+
+	list[code] = prev
+}
+
+// anyOf lists the schemas a and b, if a is already an anyOf without other fields, b is appended to it.
+func anyOf(a, b *jschema.Schema) []*jschema.Schema {
+	if len(a.AnyOf) > 0 && reflect.DeepEqual(*a, jschema.Schema{AnyOf: a.AnyOf}) {
+		for _, s := range a.AnyOf {
+			if reflect.DeepEqual(s, b) {
+				return a.AnyOf
+			}
+		}
+
+		return append(append([]*jschema.Schema{}, a.AnyOf...), b)
+	}
+
+	return []*jschema.Schema{a, b}
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+func hasStatus(list map[openapi.StatusCode]openapi.Response, code int) bool {
+	_, has := list[openapi.StatusCode(code)]
+	return has
+}
+
+// errorResDoc returns the doc of the error responses of [openapi.Error] written by the router.
+func (r *Router) errorResDoc(s jschema.Schemas, description string) openapi.Response {
+	return openapi.Response{
+		Description: description,
+		Content:     r.errorContent(s, tOpenAPIError),
+	}
+}
+
 func (g *Group) resHeaderDoc(s jschema.Schemas, t reflect.Type) openapi.Headers {
 	if t == nil {
 		return nil
@@ -272,9 +671,14 @@ func (g *Group) resHeaderDoc(s jschema.Schemas, t reflect.Type) openapi.Headers
 
 	for _, flat := range ff.Parse(t).Fields {
 		f := parseHeaderField(g.router.Schemas, flat)
+		schema := fieldSchema(s, flat.Field)
+		desc := schema.Description
+		schema.Description = ""
+
 		headers[f.name] = openapi.Header{
-			Description: f.schema.Description,
-			Schema:      s.DefineT(f.item),
+			Description: desc,
+			Schema:      schema,
+			Required:    f.required,
 		}
 	}
 
@@ -316,3 +720,7 @@ func toPathName(name string) string {
 func toQueryName(name string) string {
 	return strcase.ToSnake(name)
 }
+
+func toCookieName(name string) string {
+	return strcase.ToSnake(name)
+}
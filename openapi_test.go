@@ -12,6 +12,7 @@ import (
 	"github.com/NaturalSelectionLabs/goapi"
 	"github.com/NaturalSelectionLabs/goapi/lib/middlewares"
 	"github.com/NaturalSelectionLabs/goapi/lib/openapi"
+	"github.com/NaturalSelectionLabs/jschema"
 	"github.com/naturalselectionlabs/vary"
 	"github.com/ysmood/got"
 )
@@ -148,3 +149,78 @@ func TestAddInterfaces(t *testing.T) {
 	g.Eq(goapi.Interfaces[vary.ID(reflect.TypeOf(new(AddInterfaces)).Elem())].ID(),
 		"github.com/NaturalSelectionLabs/goapi_test.AddInterfaces")
 }
+
+func TestOpenAPIAutoMethods(t *testing.T) {
+	g := got.T(t)
+
+	r := goapi.New()
+	r.Router().AutoHEAD = true
+	r.Router().AutoOPTIONS = true
+
+	r.GET("/users/{id}", func(struct {
+		goapi.InURL
+		ID string
+	}) Res03 {
+		return Res03{}
+	})
+
+	doc := r.OpenAPI()
+	g.Len(doc.Paths["/users/{id}"], 1)
+
+	r.Router().OpenAPIAutoMethods = true
+
+	doc = r.OpenAPI()
+	p := doc.Paths["/users/{id}"]
+	g.Len(p, 3)
+	g.Eq(p[openapi.HEAD].OperationID, "")
+	g.Nil(p[openapi.HEAD].Responses[openapi.StatusOK].Content)
+	g.Eq(p[openapi.HEAD].Parameters, p[openapi.GET].Parameters)
+	g.Eq(p[openapi.OPTIONS].Parameters, p[openapi.GET].Parameters)
+	g.Eq(p[openapi.OPTIONS].Responses[openapi.StatusNoContent].Headers["Allow"].Schema.Type, jschema.TypeString)
+}
+
+func TestOpenAPICookie(t *testing.T) {
+	g := got.T(t)
+
+	r := goapi.New()
+
+	r.GET("/", func(struct {
+		goapi.InCookie
+		SessionID string `description:"session id"`
+		Theme     *string
+	}) Res03 {
+		return Res03{}
+	})
+
+	params := r.OpenAPI().Paths["/"][openapi.GET].Parameters
+	g.Len(params, 2)
+	g.Eq(params[0].Name, "session_id")
+	g.Eq(params[0].In, openapi.COOKIE)
+	g.Eq(params[0].Description, "session id")
+	g.True(params[0].Required)
+	g.Eq(params[1].Name, "theme")
+	g.False(params[1].Required)
+}
+
+func TestOpenAPIForm(t *testing.T) {
+	g := got.T(t)
+
+	r := goapi.New()
+
+	r.POST("/", func(struct {
+		goapi.InForm
+		UserName string
+		Tags     []string
+	}) Res03 {
+		return Res03{}
+	})
+
+	body := r.OpenAPI().Paths["/"][openapi.POST].RequestBody
+	g.True(body.Required)
+
+	scm := (*body.Content)[openapi.ContentTypeForm].Schema
+	g.Eq(scm.Type, jschema.TypeObject)
+	g.Eq(scm.Properties["user_name"].Type, jschema.TypeString)
+	g.Eq(scm.Properties["tags"].Type, jschema.TypeArray)
+	g.Eq(scm.Required, jschema.Required{"user_name"})
+}
@@ -1,11 +1,15 @@
 package goapi
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"mime/multipart"
 	"net/http"
 	"net/url"
 	"reflect"
 	"strings"
+	"sync/atomic"
 
 	"github.com/NaturalSelectionLabs/goapi/lib/middlewares"
 	"github.com/NaturalSelectionLabs/goapi/lib/openapi"
@@ -30,9 +34,27 @@ type Operation struct {
 
 	tRes reflect.Type
 
+	// returnsErr is true if the handler returns an error as the second return value.
+	returnsErr bool
+
 	override http.HandlerFunc
 
+	// mounted is the router that the operation forwards the requests to, check [Group.Mount].
+	mounted *mount
+
+	// middlewares is the snapshot of the operation middlewares, it's replaced as a whole by [Operation.Use].
+	middlewares atomic.Pointer[[]middlewares.Middleware]
+
+	// configOpenAPI is guarded by the lock of the router.
 	configOpenAPI ConfigOpenAPI
+
+	// schemasDefined is true if the schemas of the operation are defined by [Router.defineSchemas],
+	// schemaRefs are the names of the schemas its doc references.
+	schemasDefined bool
+	schemaRefs     []string
+
+	tree     *routeTree
+	disabled atomic.Bool
 }
 
 func (g *Group) newOperation(method openapi.Method, path string, handler OperationHandler) *Operation {
@@ -54,6 +76,7 @@ func (g *Group) newOperation(method openapi.Method, path string, handler Operati
 			group:    g,
 			method:   method,
 			path:     p,
+			vHandler: reflect.ValueOf(h),
 			override: h,
 		}
 	}
@@ -74,13 +97,7 @@ func (g *Group) newOperation(method openapi.Method, path string, handler Operati
 		params = append(params, parseParam(g.router.Schemas, p, tHandler.In(i)))
 	}
 
-	if tHandler.NumOut() != 1 {
-		panic("handler must return a single value")
-	}
-
-	tRes := tHandler.Out(0)
-
-	return &Operation{
+	op := &Operation{
 		group:    g,
 		method:   method,
 		path:     p,
@@ -88,11 +105,35 @@ func (g *Group) newOperation(method openapi.Method, path string, handler Operati
 		vHandler: vHandler,
 		tHandler: tHandler,
 		params:   params,
-		tRes:     tRes,
 	}
+
+	if op.isWS() {
+		return op.checkWS()
+	}
+
+	returnsErr := tHandler.NumOut() == 2
+
+	if returnsErr && tHandler.Out(1) != tError {
+		panic("the second return value of handler must be an error")
+	}
+
+	if tHandler.NumOut() != 1 && !returnsErr {
+		panic("handler must return a single value")
+	}
+
+	op.tRes = tHandler.Out(0)
+	op.returnsErr = returnsErr
+
+	return op
+}
+
+// describe the operation for error messages, such as "GET /users/{id} (handler getUser)".
+func (op *Operation) describe() string {
+	return fmt.Sprintf("%s %s (handler %s)", op.method, op.path.path, fnName(op.vHandler.Interface()))
 }
 
 // Handler implements the [middlewares.Middleware] interface.
+// The [Router] doesn't use it to dispatch requests, it's for using the operation standalone.
 func (op *Operation) Handler(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != op.method.String() {
@@ -100,30 +141,195 @@ func (op *Operation) Handler(next http.Handler) http.Handler {
 			return
 		}
 
-		params := op.path.match(r.URL.Path)
-		if params == nil {
+		matches := op.path.match(r.URL.Path)
+		if matches == nil {
 			next.ServeHTTP(w, r)
 			return
 		}
 
-		if op.override != nil {
-			op.override(w, r)
+		params := []string{}
+		for k, v := range matches {
+			params = append(params, k, v)
+		}
+
+		op.serve(w, r, params)
+	})
+}
+
+// Disable the operation, requests will no longer match it and it will be excluded from the OpenAPI doc.
+// It's safe to call it while the router is serving.
+func (op *Operation) Disable() {
+	op.setDisabled(true)
+}
+
+// Enable the operation disabled by [Operation.Disable].
+func (op *Operation) Enable() {
+	op.setDisabled(false)
+}
+
+func (op *Operation) setDisabled(disabled bool) {
+	r := op.group.router
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	op.disabled.Store(disabled)
+	op.tree.invalidate()
+}
+
+// Remove the operation from the router, the same method and path can be added again after it.
+// It's safe to call it while the router is serving.
+func (op *Operation) Remove() {
+	r := op.group.router
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.remove(op)
+}
+
+func (op *Operation) setMounted(m *mount) {
+	r := op.group.router
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	op.mounted = m
+}
+
+// Use middlewares that only run after the method and path of the request match the operation.
+// They wrap the parameter binding and the handler call.
+// If a middleware implements [OpenAPIMiddleware], it can modify the OpenAPI doc of the operation.
+// It's safe to call it while the router is serving.
+func (op *Operation) Use(ms ...middlewares.Middleware) *Operation {
+	r := op.group.router
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list := append([]middlewares.Middleware{}, op.chain()...)
+	list = append(list, ms...)
+	op.middlewares.Store(&list)
+
+	return op
+}
+
+// chain returns the current snapshot of the operation middlewares.
+func (op *Operation) chain() []middlewares.Middleware {
+	if list := op.middlewares.Load(); list != nil {
+		return *list
+	}
+
+	return nil
+}
+
+// serve the request that already matches the operation, params are the name value pairs of the path parameters.
+func (op *Operation) serve(w http.ResponseWriter, r *http.Request, params []string) {
+	h := http.Handler(op.override)
+
+	if op.override == nil {
+		h = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			qs := r.URL.Query()
+			for i := 0; i < len(params); i += 2 {
+				qs.Set(params[i], params[i+1])
+			}
+
+			op.handle(w, r, qs)
+		})
+	}
+
+	if list := op.chain(); len(list) > 0 {
+		h = middlewares.Chain(list...).Handler(h)
+	}
+
+	h.ServeHTTP(w, r)
+}
+
+func (op *Operation) handle(w http.ResponseWriter, r *http.Request, qs url.Values) {
+	cancel := context.CancelFunc(func() {})
+
+	if op.isWS() {
+		var ctx context.Context
+
+		ctx, cancel = context.WithCancel(r.Context())
+		defer cancel()
+
+		r = r.WithContext(ctx)
+	}
+
+	files := []multipart.File{}
+
+	defer func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}()
+
+	params, ok := op.bind(w, r, qs, &files)
+	if !ok {
+		return
+	}
+
+	if op.isWS() {
+		op.handleWS(w, r, params, cancel)
+		return
+	}
+
+	outs := op.vHandler.Call(params)
+
+	if op.returnsErr && !outs[1].IsNil() {
+		err := outs[1].Interface().(error)
+
+		router := op.group.router
+
+		mapped := router.mapError(err)
+		if mapped == nil {
+			router.writeError(w, r, http.StatusInternalServerError, router.internalError(r, err))
 			return
 		}
 
-		qs := r.URL.Query()
-		for k, v := range params {
-			qs.Set(k, v)
+		op.parseResponse(reflect.TypeOf(mapped)).write(w, r, reflect.ValueOf(mapped))
+
+		return
+	}
+
+	res := outs[0]
+
+	resType := res.Type()
+	if resType.Kind() == reflect.Interface {
+		setType := resType
+		res = res.Elem()
+		resType = res.Type()
+
+		if _, ok := Interfaces[vary.ID(setType)]; !ok {
+			panic(fmt.Sprintf("handler response of path `%s` must goapi.Interface(new(%s))", op.path.path, setType.String()))
 		}
 
-		op.handle(w, r, qs)
-	})
+		if _, ok := Interfaces[vary.ID(setType)].Implementations[vary.ID(resType)]; !ok {
+			panic(fmt.Sprintf("handler response of path `%s` must goapi.Interface(new(%s), %s{})",
+				op.path.path, setType.String(), resType.String()))
+		}
+	}
+
+	op.parseResponse(resType).write(w, r, res)
 }
 
-func (op *Operation) handle(w http.ResponseWriter, r *http.Request, qs url.Values) {
+// bind the params of the handler from the request, the opened files are appended to the files.
+// It responds the error and returns false if any param is invalid.
+func (op *Operation) bind(w http.ResponseWriter, r *http.Request, qs url.Values,
+	files *[]multipart.File,
+) ([]reflect.Value, bool) {
 	params := []reflect.Value{}
+	errs := paramsError{}
 
 	for _, p := range op.params {
+		if p.isWebSocket {
+			// it's set after the connection is upgraded
+			params = append(params, reflect.Value{})
+
+			continue
+		}
+
 		if p.isContext {
 			params = append(params, reflect.ValueOf(r.Context()))
 
@@ -145,39 +351,45 @@ func (op *Operation) handle(w http.ResponseWriter, r *http.Request, qs url.Value
 			param, err = p.loadHeader(r.Header)
 		case inURL:
 			param, err = p.loadURL(qs)
+		case inCookie:
+			param, err = p.loadCookie(r.Cookies())
+		case inForm:
+			param, err = p.loadFormBody(w, r)
+		case inMultipart:
+			param, err = p.loadMultipart(r, files)
 		case inBody:
-			param, err = p.loadBody(r.Body)
+			param, err = p.loadBody(op.group.router.decoder(p.param, r.Header.Get("Content-Type")), r.Body)
 		}
 
-		if err != nil {
-			middlewares.ResponseError(w, http.StatusBadRequest, &openapi.Error{
-				Code:    openapi.CodeInvalidParam,
-				Message: err.Error(),
+		var pe paramsError
+
+		switch {
+		case errors.Is(err, errUnsupportedMediaType):
+			op.group.router.writeError(w, r, http.StatusUnsupportedMediaType, &openapi.Error{
+				Code:    openapi.CodeUnsupportedMediaType,
+				Message: fmt.Sprintf("unsupported media type: %s", r.Header.Get("Content-Type")),
+				Target:  "Content-Type",
 			})
 
-			return
+			return nil, false
+		case errors.As(err, &pe):
+			errs = append(errs, pe...)
+		case err != nil:
+			errs.add(openapi.CodeInvalidParam, "", err.Error())
 		}
 
 		params = append(params, param)
 	}
 
-	res := op.vHandler.Call(params)[0]
+	if len(errs) > 0 {
+		op.group.router.writeError(w, r, http.StatusBadRequest, &openapi.Error{
+			Code:    openapi.CodeInvalidParam,
+			Message: errs.Error(),
+			Details: errs,
+		})
 
-	resType := res.Type()
-	if resType.Kind() == reflect.Interface {
-		setType := resType
-		res = res.Elem()
-		resType = res.Type()
-
-		if _, ok := Interfaces[vary.ID(setType)]; !ok {
-			panic(fmt.Sprintf("handler response of path `%s` must goapi.Interface(new(%s))", op.path.path, setType.String()))
-		}
-
-		if _, ok := Interfaces[vary.ID(setType)].Implementations[vary.ID(resType)]; !ok {
-			panic(fmt.Sprintf("handler response of path `%s` must goapi.Interface(new(%s), %s{})",
-				op.path.path, setType.String(), resType.String()))
-		}
+		return nil, false
 	}
 
-	op.parseResponse(resType).write(w, res)
+	return params, true
 }
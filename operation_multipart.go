@@ -0,0 +1,338 @@
+package goapi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+
+	ff "github.com/NaturalSelectionLabs/goapi/lib/flat-fields"
+	"github.com/NaturalSelectionLabs/goapi/lib/openapi"
+	"github.com/NaturalSelectionLabs/jschema"
+)
+
+// InMultipart is a flag that can be embedded into a struct to mark it
+// as a container for the multipart/form-data parameters of the request body.
+// A field of type [File], *[File] or [][File] receives the uploaded files of the part with the same name,
+// other fields are handled the same as [InForm].
+// Use the tag `maxSize` to limit the size of each file in bytes, such as `maxSize:"1048576"`,
+// use the tag `accept` to limit the content types of the files, such as `accept:"image/png, image/*"`.
+// The body is read part by part, the reading stops as soon as a file breaks the limits,
+// the other parts are limited to 10MB in total, and the files of unknown names are skipped.
+type InMultipart struct{}
+
+func (InMultipart) inMultipart() paramsInGuard { return struct{}{} }
+
+// File is an uploaded file of a multipart request, check [InMultipart].
+// It's only readable during the call of the handler.
+type File struct {
+	io.Reader
+
+	Filename    string
+	ContentType string
+	Size        int64
+}
+
+var tFile = reflect.TypeOf(File{})
+
+// The max memory to store the files of a multipart body, the rest will be stored in temporary files.
+const multipartMemory = 32 << 20
+
+type parsedFile struct {
+	name      string
+	flatField *ff.FlattenedField
+	ptr       bool
+	slice     bool
+	maxSize   int64
+	accept    []string
+}
+
+func isFileType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+
+	return t == tFile
+}
+
+func parseFileField(flatField *ff.FlattenedField) *parsedFile {
+	f := flatField.Field
+
+	parsed := &parsedFile{
+		name:      tagName(f.Tag, toQueryName(f.Name)),
+		flatField: flatField,
+		ptr:       f.Type.Kind() == reflect.Ptr,
+		slice:     f.Type.Kind() == reflect.Slice,
+	}
+
+	if v, has := f.Tag.Lookup("maxSize"); has {
+		size, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			panic(fmt.Sprintf("invalid maxSize tag of field %s: %v", f.Name, err))
+		}
+
+		parsed.maxSize = size
+	}
+
+	if v, has := f.Tag.Lookup("accept"); has {
+		for _, t := range strings.Split(v, ",") {
+			parsed.accept = append(parsed.accept, strings.TrimSpace(t))
+		}
+	}
+
+	return parsed
+}
+
+func (p *parsedParam) loadMultipart(r *http.Request, files *[]multipart.File) (reflect.Value, error) {
+	values, parts, err := p.readMultipart(r, files)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+
+	errs := paramsError{}
+	ptr := reflect.New(p.param)
+
+	val, err := p.loadURL(values)
+	if err != nil {
+		errs = append(errs, err.(paramsError)...)
+	} else {
+		ptr.Elem().Set(val)
+	}
+
+	for _, f := range p.files {
+		list := parts[f.name]
+
+		if len(list) == 0 {
+			if !f.ptr && !f.slice {
+				errs.add(openapi.CodeRequired, "multipart:"+f.name, fmt.Sprintf("missing multipart file `%s`", f.name))
+			}
+
+			continue
+		}
+
+		switch {
+		case f.slice:
+			f.flatField.Set(ptr, reflect.ValueOf(list))
+		case f.ptr:
+			f.flatField.Set(ptr, reflect.ValueOf(&list[0]))
+		default:
+			f.flatField.Set(ptr, reflect.ValueOf(list[0]))
+		}
+	}
+
+	if err := errs.err(); err != nil {
+		return reflect.Value{}, err
+	}
+
+	return ptr.Elem(), nil
+}
+
+// readMultipart streams the parts of the body, the size and the content type of each file are checked
+// while it's being read, so an invalid file stops the reading immediately.
+// It returns errUnsupportedMediaType if the body isn't a multipart form.
+// The opened files are appended to the files.
+func (p *parsedParam) readMultipart(r *http.Request, files *[]multipart.File) (url.Values, map[string][]File, error) {
+	if t, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); t != openapi.ContentTypeMultipart {
+		return nil, nil, errUnsupportedMediaType
+	}
+
+	mr, err := r.MultipartReader()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse multipart body: %w", err)
+	}
+
+	values := url.Values{}
+	parts := map[string][]File{}
+	valuesSize := int64(0)
+	memory := int64(multipartMemory)
+
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			return values, parts, nil
+		} else if err != nil {
+			return nil, nil, fmt.Errorf("failed to parse multipart body: %w", err)
+		}
+
+		name := part.FormName()
+
+		if part.FileName() == "" {
+			b, err := io.ReadAll(io.LimitReader(part, maxFormSize-valuesSize+1))
+			if err != nil {
+				return nil, nil, fmt.Errorf("failed to parse multipart body: %w", err)
+			}
+
+			valuesSize += int64(len(b))
+			if valuesSize > maxFormSize {
+				return nil, nil, fmt.Errorf("multipart values exceed the max size %d bytes", maxFormSize)
+			}
+
+			values.Add(name, string(b))
+
+			continue
+		}
+
+		f := p.file(name)
+		if f == nil {
+			continue
+		}
+
+		file, code, err := f.read(part, &memory)
+		if err != nil {
+			errs := paramsError{}
+			errs.add(code, "multipart:"+name, err.Error())
+
+			return nil, nil, errs
+		}
+
+		*files = append(*files, file.Reader.(multipart.File))
+		parts[name] = append(parts[name], file)
+	}
+}
+
+// file returns the file field of the part name, nil if not found.
+func (p *parsedParam) file(name string) *parsedFile {
+	for _, f := range p.files {
+		if f.name == name {
+			return f
+		}
+	}
+
+	return nil
+}
+
+// read the file of the part, the content is kept in memory until the memory budget is used up,
+// the rest is stored in a temporary file. The returned code is the code of the error.
+func (f *parsedFile) read(part *multipart.Part, memory *int64) (File, openapi.Code, error) {
+	contentType := part.Header.Get("Content-Type")
+
+	if len(f.accept) > 0 && !f.accepts(contentType) {
+		return File{}, openapi.CodeEnum,
+			fmt.Errorf("multipart file `%s` has unaccepted content type `%s`", f.name, contentType)
+	}
+
+	body := io.Reader(part)
+	if f.maxSize > 0 {
+		body = io.LimitReader(part, f.maxSize+1)
+	}
+
+	buf := bytes.NewBuffer(nil)
+
+	size, err := io.CopyN(buf, body, *memory+1)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return File{}, openapi.CodeInvalidParam, fmt.Errorf("failed to read multipart file `%s`: %w", f.name, err)
+	}
+
+	var file multipart.File
+
+	if size > *memory {
+		file, size, err = spill(io.MultiReader(buf, body))
+		if err != nil {
+			return File{}, openapi.CodeInvalidParam, fmt.Errorf("failed to read multipart file `%s`: %w", f.name, err)
+		}
+	} else {
+		*memory -= size
+		file = memoryFile{bytes.NewReader(buf.Bytes())}
+	}
+
+	if f.maxSize > 0 && size > f.maxSize {
+		_ = file.Close()
+		return File{}, openapi.CodeMax, fmt.Errorf("multipart file `%s` exceeds the max size %d bytes", f.name, f.maxSize)
+	}
+
+	return File{
+		Reader:      file,
+		Filename:    part.FileName(),
+		ContentType: contentType,
+		Size:        size,
+	}, 0, nil
+}
+
+type memoryFile struct {
+	*bytes.Reader
+}
+
+func (memoryFile) Close() error { return nil }
+
+// tempFile is removed when it's closed.
+type tempFile struct {
+	*os.File
+}
+
+func (f tempFile) Close() error {
+	_ = f.File.Close()
+	return os.Remove(f.Name())
+}
+
+// spill the content of r to a temporary file.
+func spill(r io.Reader) (multipart.File, int64, error) {
+	tmp, err := os.CreateTemp("", "goapi-multipart-")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	file := tempFile{tmp}
+
+	size, err := io.Copy(tmp, r)
+	if err == nil {
+		_, err = tmp.Seek(0, io.SeekStart)
+	}
+
+	if err != nil {
+		_ = file.Close()
+		return nil, 0, err
+	}
+
+	return file, size, nil
+}
+
+// accepts returns true if the content type matches one of the accept patterns, such as "image/*".
+func (f *parsedFile) accepts(contentType string) bool {
+	t, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+
+	for _, a := range f.accept {
+		if a == t || a == "*/*" || (strings.HasSuffix(a, "/*") && strings.HasPrefix(t, a[:len(a)-1])) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func multipartSchema(s jschema.Schemas, p *parsedParam) *jschema.Schema {
+	scm := formSchema(s, p)
+
+	for _, f := range p.files {
+		file := &jschema.Schema{
+			Type:        jschema.TypeString,
+			Format:      "binary",
+			Description: f.flatField.Field.Tag.Get(string(jschema.JTagDescription)),
+		}
+
+		if f.slice {
+			scm.Properties[f.name] = &jschema.Schema{
+				Type:  jschema.TypeArray,
+				Items: file,
+			}
+		} else {
+			scm.Properties[f.name] = file
+		}
+
+		if !f.ptr && !f.slice {
+			scm.Required = append(scm.Required, f.name)
+		}
+	}
+
+	return scm
+}
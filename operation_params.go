@@ -3,13 +3,16 @@ package goapi
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"net/url"
 	"reflect"
 
 	ff "github.com/NaturalSelectionLabs/goapi/lib/flat-fields"
+	"github.com/NaturalSelectionLabs/goapi/lib/openapi"
 	"github.com/NaturalSelectionLabs/jschema"
 	"github.com/xeipuuv/gojsonschema"
 )
@@ -19,6 +22,9 @@ type paramsIn int
 const (
 	inHeader paramsIn = iota + 1
 	inURL
+	inCookie
+	inForm
+	inMultipart
 	inBody
 )
 
@@ -36,6 +42,23 @@ type InURL struct{}
 
 func (InURL) inURL() paramsInGuard { return struct{}{} }
 
+// InCookie is a flag that can be embedded into a struct to mark it
+// as a container for request cookie parameters.
+type InCookie struct{}
+
+func (InCookie) inCookie() paramsInGuard { return struct{}{} }
+
+// InForm is a flag that can be embedded into a struct to mark it
+// as a container for the urlencoded form parameters of the request body.
+// The Content-Type of the request must be "application/x-www-form-urlencoded",
+// the body is limited to 10MB the same as [http.Request.ParseForm].
+type InForm struct{}
+
+// maxFormSize is the max size of the urlencoded form body.
+const maxFormSize = 10 << 20
+
+func (InForm) inForm() paramsInGuard { return struct{}{} }
+
 var tContext = reflect.TypeOf(new(context.Context)).Elem()
 
 var tRequest = reflect.TypeOf((*http.Request)(nil))
@@ -44,84 +67,145 @@ type parsedParam struct {
 	in     paramsIn
 	param  reflect.Type
 	fields []*parsedField
+	files  []*parsedFile
 
-	isContext bool
-	isRequest bool
+	isContext   bool
+	isRequest   bool
+	isWebSocket bool
 
 	bodyValidator *gojsonschema.Schema
 }
 
-func (p *parsedParam) loadURL(qs url.Values) (reflect.Value, error) { //nolint: gocognit
+func (p *parsedParam) loadURL(qs url.Values) (reflect.Value, error) {
 	val := reflect.New(p.param)
+	errs := paramsError{}
 
 	for _, f := range p.fields {
-		var (
-			fv  reflect.Value
-			err error
-		)
+		fv, code, err := f.value(qs, p.location(f))
+		if err != nil {
+			errs.add(code, p.target(f), err.Error())
+			continue
+		}
 
-		if f.name == "path" {
-			vs, has := qs["*"]
-			if !has {
-				continue
+		if f.ptr && !f.slice {
+			if fv.IsValid() {
+				c := reflect.New(f.item)
+				c.Elem().Set(fv)
+				f.flatField.Set(val, c)
 			}
+		} else if fv.IsValid() {
+			f.flatField.Set(val, fv)
+		}
 
-			fv, err = toValue(f.item, vs[0])
-			if err != nil {
-				return reflect.Value{}, fmt.Errorf("failed to parse url path param `%s`: %w", f.name, err)
-			}
-		} else if !f.InPath && f.slice {
-			vs, has := qs[f.name]
-			if has { //nolint: gocritic
-				fv = reflect.MakeSlice(f.sliceType, len(vs), len(vs))
-			} else if f.hasDefault {
-				fv = f.defaultVal
-			} else {
-				continue
-			}
+		f.validate(val, p.target(f), &errs)
+	}
 
-			for i, v := range vs {
-				val, err := toValue(f.item, v)
-				if err != nil {
-					return reflect.Value{}, fmt.Errorf("failed to parse url param `%s`: %w", f.name, err)
-				}
+	if err := errs.err(); err != nil {
+		return reflect.Value{}, err
+	}
 
-				fv.Index(i).Set(val)
-			}
-		} else {
-			vs, has := qs[f.name]
-			if has { //nolint: gocritic
-				fv, err = toValue(f.item, vs[0])
-				if err != nil {
-					return reflect.Value{}, fmt.Errorf("failed to parse url path param `%s`: %w", f.name, err)
-				}
-			} else if f.required {
-				if !f.InPath {
-					return reflect.Value{}, fmt.Errorf("missing url query param `%s`", f.name)
-				}
+	return val.Elem(), nil
+}
+
+// value returns the value of the field from qs, it's invalid if the field should be left as zero value.
+// The returned code is the code of the error, loc is where the field is read from, such as "cookie".
+func (f *parsedField) value(qs url.Values, loc string) (reflect.Value, openapi.Code, error) {
+	if f.name == "path" {
+		vs, has := qs["*"]
+		if !has {
+			return reflect.Value{}, 0, nil
+		}
+
+		fv, err := toValue(f.item, vs[0])
+		if err != nil {
+			return reflect.Value{}, openapi.CodeType, fmt.Errorf("failed to parse url path param `%s`: %w", f.name, err)
+		}
+
+		return fv, 0, nil
+	}
 
-				return reflect.Value{}, fmt.Errorf("missing url path param `%s`", f.name)
-			} else if f.hasDefault {
-				fv = f.defaultVal
+	vs, has := qs[f.name]
+
+	if !f.InPath && f.slice {
+		if !has {
+			if f.hasDefault {
+				return f.defaultVal, 0, nil
 			}
+
+			return reflect.Value{}, 0, nil
 		}
 
-		if f.ptr && !f.slice {
-			if fv.IsValid() {
-				c := reflect.New(f.item)
-				c.Elem().Set(fv)
-				f.flatField.Set(val, c)
+		fv := reflect.MakeSlice(f.sliceType, len(vs), len(vs))
+
+		for i, v := range vs {
+			val, err := toValue(f.item, v)
+			if err != nil {
+				return reflect.Value{}, openapi.CodeType, fmt.Errorf("failed to parse url param `%s`: %w", f.name, err)
 			}
-		} else {
-			f.flatField.Set(val, fv)
+
+			fv.Index(i).Set(val)
+		}
+
+		return fv, 0, nil
+	}
+
+	switch {
+	case has:
+		fv, err := toValue(f.item, vs[0])
+		if err != nil {
+			return reflect.Value{}, openapi.CodeType, fmt.Errorf("failed to parse url path param `%s`: %w", f.name, err)
+		}
+
+		return fv, 0, nil
+
+	case f.required:
+		return reflect.Value{}, openapi.CodeRequired, fmt.Errorf("missing %s `%s`", loc, f.name)
+
+	case f.hasDefault:
+		return f.defaultVal, 0, nil
+	}
+
+	return reflect.Value{}, 0, nil
+}
+
+// target returns the target of the errors of the field, such as "query:id" or "header:x-token".
+func (p *parsedParam) target(f *parsedField) string {
+	switch p.in {
+	case inHeader:
+		return "header:" + f.name
+	case inCookie:
+		return "cookie:" + f.name
+	case inForm:
+		return "form:" + f.name
+	case inMultipart:
+		return "multipart:" + f.name
+	case inURL:
+		if f.InPath {
+			return "path:" + f.name
 		}
+	}
 
-		if err := f.validate(val); err != nil {
-			return reflect.Value{}, err
+	return "query:" + f.name
+}
+
+// location returns the human readable location of the field, such as "url query param" or "cookie".
+func (p *parsedParam) location(f *parsedField) string {
+	switch p.in {
+	case inHeader:
+		return "header"
+	case inCookie:
+		return "cookie"
+	case inForm:
+		return "form field"
+	case inMultipart:
+		return "multipart field"
+	case inURL:
+		if f.InPath {
+			return "url path param"
 		}
 	}
 
-	return val.Elem(), nil
+	return "url query param"
 }
 
 func (p *parsedParam) loadHeader(h http.Header) (reflect.Value, error) {
@@ -137,23 +221,89 @@ func (p *parsedParam) loadHeader(h http.Header) (reflect.Value, error) {
 	return p.loadURL(qs)
 }
 
-func (p *parsedParam) loadBody(body io.Reader) (reflect.Value, error) {
+func (p *parsedParam) loadCookie(cookies []*http.Cookie) (reflect.Value, error) {
+	qs := url.Values{}
+
+	for _, c := range cookies {
+		qs.Add(c.Name, c.Value)
+	}
+
+	return p.loadURL(qs)
+}
+
+// loadFormBody checks the Content-Type of the request and loads the form body with the size limit.
+func (p *parsedParam) loadFormBody(w http.ResponseWriter, r *http.Request) (reflect.Value, error) {
+	if t, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); t != openapi.ContentTypeForm {
+		return reflect.Value{}, errUnsupportedMediaType
+	}
+
+	return p.loadForm(http.MaxBytesReader(w, r.Body, maxFormSize))
+}
+
+func (p *parsedParam) loadForm(body io.Reader) (reflect.Value, error) {
+	b, err := io.ReadAll(body)
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("failed to read form body: %w", err)
+	}
+
+	qs, err := url.ParseQuery(string(b))
+	if err != nil {
+		return reflect.Value{}, fmt.Errorf("failed to parse form body: %w", err)
+	}
+
+	return p.loadURL(qs)
+}
+
+// errUnsupportedMediaType is returned when the Content-Type of the request body isn't supported by the param.
+var errUnsupportedMediaType = errors.New("unsupported media type")
+
+// loadBody decodes the body with d, it returns errUnsupportedMediaType if d is nil.
+func (p *parsedParam) loadBody(d *bodyDecoder, body io.Reader) (reflect.Value, error) {
+	if d == nil {
+		return reflect.Value{}, errUnsupportedMediaType
+	}
+
 	val := reflect.New(p.param)
 	ref := val.Interface()
 
-	err := json.NewDecoder(body).Decode(&ref)
+	errs := paramsError{}
+
+	err := d.decoder.Decode(body, ref)
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		errs.add(openapi.CodeType, "body:"+fieldPointer(typeErr.Field),
+			fmt.Sprintf("failed to parse %s body: %s", d.name(), err))
+		return reflect.Value{}, errs
+	}
+
 	if err != nil {
-		return reflect.Value{}, fmt.Errorf("failed to parse json body: %w", err)
+		errs.add(openapi.CodeInvalidParam, "body:/", fmt.Sprintf("failed to parse %s body: %s", d.name(), err))
+		return reflect.Value{}, errs
 	}
 
 	check, _ := p.bodyValidator.Validate(gojsonschema.NewGoLoader(ref))
 	if !check.Valid() {
-		return reflect.Value{}, fmt.Errorf("request body is invalid: %v", check.Errors())
+		errs.addResult(check, "request body is invalid: ", func(re gojsonschema.ResultError) string {
+			return "body:" + jsonPointer(re)
+		})
+		return reflect.Value{}, errs
 	}
 
 	return val.Elem(), nil
 }
 
+// hasParams returns true if the operation binds any params from the request.
+func (op *Operation) hasParams() bool {
+	for _, p := range op.params {
+		if !p.isContext && !p.isRequest && !p.isWebSocket {
+			return true
+		}
+	}
+
+	return false
+}
+
 type parsedField struct {
 	name       string // the normalized name of the field
 	item       reflect.Type
@@ -163,6 +313,7 @@ type parsedField struct {
 	sliceType  reflect.Type
 	required   bool
 	InPath     bool
+	pathParam  *pathParam
 	hasDefault bool
 	defaultVal reflect.Value
 
@@ -170,15 +321,15 @@ type parsedField struct {
 	validator *gojsonschema.Schema
 }
 
-func (f *parsedField) validate(val reflect.Value) error {
+func (f *parsedField) validate(val reflect.Value, target string, errs *paramsError) {
 	v := f.flatField.Get(val).Interface()
 	res, _ := f.validator.Validate(gojsonschema.NewGoLoader(v))
 
 	if !res.Valid() {
-		return fmt.Errorf("param `%s` is invalid: %v", f.name, res.Errors())
+		errs.addResult(res, fmt.Sprintf("param `%s` is invalid: ", f.name), func(gojsonschema.ResultError) string {
+			return target
+		})
 	}
-
-	return nil
 }
 
 func parseParam(s jschema.Schemas, path *Path, p reflect.Type) *parsedParam {
@@ -190,6 +341,10 @@ func parseParam(s jschema.Schemas, path *Path, p reflect.Type) *parsedParam {
 		return &parsedParam{isRequest: true}
 	}
 
+	if p.Implements(tWebSocket) {
+		return parseWebSocketParam(s, p)
+	}
+
 	type InHeader interface {
 		inHeader() paramsInGuard
 	}
@@ -198,6 +353,18 @@ func parseParam(s jschema.Schemas, path *Path, p reflect.Type) *parsedParam {
 		inURL() paramsInGuard
 	}
 
+	type InCookie interface {
+		inCookie() paramsInGuard
+	}
+
+	type InForm interface {
+		inForm() paramsInGuard
+	}
+
+	type InMultipart interface {
+		inMultipart() paramsInGuard
+	}
+
 	parsed := &parsedParam{param: p}
 	fields := []*parsedField{}
 	flat := ff.Parse(p)
@@ -210,6 +377,31 @@ func parseParam(s jschema.Schemas, path *Path, p reflect.Type) *parsedParam {
 			fields = append(fields, parseHeaderField(s, f))
 		}
 
+	case InCookie:
+		parsed.in = inCookie
+
+		for _, f := range flat.Fields {
+			fields = append(fields, parseCookieField(s, f))
+		}
+
+	case InForm:
+		parsed.in = inForm
+
+		for _, f := range flat.Fields {
+			fields = append(fields, parseFormField(s, f))
+		}
+
+	case InMultipart:
+		parsed.in = inMultipart
+
+		for _, f := range flat.Fields {
+			if isFileType(f.Field.Type) {
+				parsed.files = append(parsed.files, parseFileField(f))
+			} else {
+				fields = append(fields, parseFormField(s, f))
+			}
+		}
+
 	case InURL:
 		parsed.in = inURL
 
@@ -254,6 +446,24 @@ func parseHeaderField(s jschema.Schemas, flatField *ff.FlattenedField) *parsedFi
 	return parsed
 }
 
+func parseCookieField(s jschema.Schemas, flatField *ff.FlattenedField) *parsedField {
+	f := flatField.Field
+	parsed := parseField(s, flatField)
+	parsed.name = toCookieName(f.Name)
+	parsed.name = tagName(f.Tag, parsed.name)
+
+	return parsed
+}
+
+func parseFormField(s jschema.Schemas, flatField *ff.FlattenedField) *parsedField {
+	f := flatField.Field
+	parsed := parseField(s, flatField)
+	parsed.name = toQueryName(f.Name)
+	parsed.name = tagName(f.Tag, parsed.name)
+
+	return parsed
+}
+
 func parseURLField(s jschema.Schemas, path *Path, flatField *ff.FlattenedField) *parsedField {
 	f := parseField(s, flatField)
 
@@ -274,6 +484,7 @@ func parseURLField(s jschema.Schemas, path *Path, flatField *ff.FlattenedField)
 		}
 
 		f.InPath = true
+		f.pathParam = path.param(f.name)
 	} else {
 		f.name = toQueryName(t.Name)
 	}
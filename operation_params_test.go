@@ -7,6 +7,7 @@ import (
 	"reflect"
 	"testing"
 
+	"github.com/NaturalSelectionLabs/goapi/lib/openapi"
 	"github.com/NaturalSelectionLabs/jschema"
 	"github.com/ysmood/got"
 )
@@ -18,6 +19,10 @@ func Test_paramsInGuard(t *testing.T) {
 
 	_ = InURL{}.inURL()
 
+	_ = InCookie{}.inCookie()
+
+	_ = InForm{}.inForm()
+
 	g.Eq(1, 1)
 }
 
@@ -163,7 +168,11 @@ func Test_loadURL_err(t *testing.T) {
 
 	_, err = parsed.loadURL(url.Values{"a": {"true"}})
 	g.Eq(err.Error(), "failed to parse url path param `a`: can't parse `true` to expected value, "+
-		"json: cannot unmarshal bool into Go value of type int")
+		"json: cannot unmarshal bool into Go value of type int; missing url query param `b`")
+	g.Eq(err.(paramsError)[0].Code, openapi.CodeType)
+	g.Eq(err.(paramsError)[0].Target, "path:a")
+	g.Eq(err.(paramsError)[1].Code, openapi.CodeRequired)
+	g.Eq(err.(paramsError)[1].Target, "query:b")
 
 	_, err = parsed.loadURL(url.Values{"a": {"1"}, "b": {"2"}, "c": {"true"}})
 	g.Eq(err.Error(), "failed to parse url param `c`: can't parse `true` to expected value, "+
@@ -216,6 +225,44 @@ func Test_loadHeader(t *testing.T) {
 	})
 }
 
+func Test_loadCookie(t *testing.T) {
+	g := got.T(t)
+
+	type cookie struct {
+		InCookie
+		SessionID string
+		Theme     string `default:"\"light\""`
+		Age       *int   `json:"a"`
+	}
+
+	s := jschema.New("")
+
+	parsed := parseParam(s, nil, reflect.TypeOf(cookie{}))
+
+	v, err := parsed.loadCookie([]*http.Cookie{
+		{Name: "session_id", Value: "abc"},
+		{Name: "a", Value: "10"},
+	})
+	g.E(err)
+
+	age := 10
+
+	g.Eq(v.Interface(), cookie{
+		SessionID: "abc",
+		Theme:     "light",
+		Age:       &age,
+	})
+
+	_, err = parsed.loadCookie([]*http.Cookie{{Name: "a", Value: "10"}})
+	g.Eq(err.Error(), "missing cookie `session_id`")
+
+	_, err = parsed.loadCookie([]*http.Cookie{
+		{Name: "session_id", Value: "abc"},
+		{Name: "a", Value: "x"},
+	})
+	g.Has(err.Error(), "failed to parse url path param `a`")
+}
+
 func strPtr(s string) *string {
 	return &s
 }
@@ -232,7 +279,9 @@ func Test_loadBody(t *testing.T) {
 
 	parsed := parseParam(s, nil, reflect.TypeOf(body{}))
 
-	v, err := parsed.loadBody(bytes.NewBufferString(`{"id": 1, "name": "test"}`))
+	d := &bodyDecoder{openapi.ContentTypeJSON, DecoderJSON}
+
+	v, err := parsed.loadBody(d, bytes.NewBufferString(`{"id": 1, "name": "test"}`))
 	g.E(err)
 
 	g.Eq(v.Interface(), body{
@@ -240,8 +289,63 @@ func Test_loadBody(t *testing.T) {
 		Name: "test",
 	})
 
-	_, err = parsed.loadBody(bytes.NewBufferString(`{`))
+	_, err = parsed.loadBody(d, bytes.NewBufferString(`{`))
 	g.Eq(err.Error(), "failed to parse json body: unexpected EOF")
+
+	_, err = parsed.loadBody(d, bytes.NewBufferString(`{"id": "x"}`))
+	g.Eq(err.(paramsError)[0].Code, openapi.CodeType)
+	g.Eq(err.(paramsError)[0].Target, "body:/id")
+
+	type nested struct {
+		Obj *struct {
+			A int `json:"a" min:"1"`
+		} `json:"obj"`
+	}
+
+	parsed = parseParam(s, nil, reflect.TypeOf(nested{}))
+
+	_, err = parsed.loadBody(d, bytes.NewBufferString(`{"obj": {"a": "x"}}`))
+	g.Eq(err.(paramsError)[0].Target, "body:/obj/a")
+
+	_, err = parsed.loadBody(d, bytes.NewBufferString(`{"obj": {"a": 0}}`))
+	g.Eq(err.(paramsError), paramsError{{
+		Code:    openapi.CodeMin,
+		Target:  "body:/obj/a",
+		Message: "request body is invalid: obj.a: Must be greater than or equal to 1",
+	}})
+}
+
+func Test_loadForm(t *testing.T) {
+	g := got.T(t)
+
+	type form struct {
+		InForm
+		UserName string
+		Tags     []string
+		Age      int `default:"18" min:"1"`
+	}
+
+	s := jschema.New("")
+
+	parsed := parseParam(s, nil, reflect.TypeOf(form{}))
+
+	v, err := parsed.loadForm(bytes.NewBufferString("user_name=a%20b&tags=x&tags=y"))
+	g.E(err)
+
+	g.Eq(v.Interface(), form{
+		UserName: "a b",
+		Tags:     []string{"x", "y"},
+		Age:      18,
+	})
+
+	_, err = parsed.loadForm(bytes.NewBufferString("tags=x"))
+	g.Eq(err.Error(), "missing form field `user_name`")
+
+	_, err = parsed.loadForm(bytes.NewBufferString("user_name=a&age=0"))
+	g.Has(err.Error(), "param `age` is invalid")
+
+	_, err = parsed.loadForm(bytes.NewBufferString("%"))
+	g.Has(err.Error(), "failed to parse form body")
 }
 
 func Test_parseResponse_err(t *testing.T) {
@@ -336,7 +440,7 @@ func Test_custom_checker(t *testing.T) {
 	g.Nil(err)
 
 	_, err = parsed.loadURL(url.Values{"id": {"no"}})
-	g.Eq(err.Error(), "param `id` is invalid: [(root): Does not match format 'my-id']")
+	g.Eq(err.Error(), "param `id` is invalid: Does not match format 'my-id'")
 }
 
 func Test_validation(t *testing.T) {
@@ -354,8 +458,8 @@ func Test_validation(t *testing.T) {
 	{
 		parsed := parseParam(s, path, reflect.TypeOf(A{}))
 
-		_, err := parsed.loadBody(bytes.NewBufferString(`{"id": "ok"}`))
-		g.Eq(err.Error(), "request body is invalid: [ID: String length must be greater than or equal to 5]")
+		_, err := parsed.loadBody(&bodyDecoder{openapi.ContentTypeJSON, DecoderJSON}, bytes.NewBufferString(`{"id": "ok"}`))
+		g.Eq(err.Error(), "request body is invalid: ID: String length must be greater than or equal to 5")
 	}
 
 	{
@@ -366,9 +470,9 @@ func Test_validation(t *testing.T) {
 
 		parsed := parseParam(s, path, reflect.TypeOf(B{}))
 		_, err := parsed.loadURL(url.Values{"id": {"0"}})
-		g.Eq(err.Error(), "param `id` is invalid: [(root): Must be greater than or equal to 1]")
+		g.Eq(err.Error(), "param `id` is invalid: Must be greater than or equal to 1")
 
 		_, err = parsed.loadURL(url.Values{"id": {"20"}})
-		g.Eq(err.Error(), "param `id` is invalid: [(root): Must be less than or equal to 10]")
+		g.Eq(err.Error(), "param `id` is invalid: Must be less than or equal to 10")
 	}
 }
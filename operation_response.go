@@ -1,11 +1,16 @@
 package goapi
 
 import (
-	"encoding/json"
+	"bytes"
+	"fmt"
 	"io"
+	"io/fs"
 	"net/http"
 	"reflect"
+	"strconv"
+	"time"
 
+	ff "github.com/NaturalSelectionLabs/goapi/lib/flat-fields"
 	"github.com/NaturalSelectionLabs/goapi/lib/openapi"
 )
 
@@ -20,7 +25,12 @@ var tResponse = reflect.TypeOf((*Response)(nil)).Elem()
 
 // DataStream is a flag for binary response body.
 // When Data field in the response struct is of this type,
-// the response body will be written directly to the [http.ResponseWriter].
+// the response body will be written directly to the [http.ResponseWriter] from the current offset of the data.
+// If the status code is 200 and the data is an [io.ReadSeeker] that opts in by a ModTime, Stat, or Size method,
+// such as an [os.File], or the response has the ETag header, the whole data is written by [http.ServeContent],
+// so the Range and conditional requests are handled.
+// The modification time comes from the ModTime or Stat method of the data if it has one,
+// the ETag comes from the ETag header of the response.
 type DataStream io.Reader
 
 var tDataStream = reflect.TypeOf(new(DataStream)).Elem()
@@ -35,14 +45,18 @@ type parsedRes struct {
 	isDirect   bool
 
 	isStream    bool
+	isSSE       bool
+	isItems     bool
+	isETagger   bool
 	contentType string
 
 	typ reflect.Type
 
-	header reflect.Type
-	err    reflect.Type
-	data   reflect.Type
-	meta   reflect.Type
+	header  reflect.Type
+	headers []*resHeader
+	err     reflect.Type
+	data    reflect.Type
+	meta    reflect.Type
 }
 
 const (
@@ -64,9 +78,11 @@ func (op *Operation) parseResponse(t reflect.Type) *parsedRes {
 	if header, has := t.FieldByName("Header"); has {
 		res.hasHeader = true
 		res.header = header.Type
+		res.headers = parseResHeaders(header.Type)
 	}
 
 	res.contentType = getContentType(t, "")
+	res.isETagger = t.Implements(tETagger)
 
 	if err, has := t.FieldByName("Error"); has {
 		res.hasErr = true
@@ -82,6 +98,14 @@ func (op *Operation) parseResponse(t reflect.Type) *parsedRes {
 			res.isStream = true
 		}
 
+		if f.Type.Implements(tSSEStream) {
+			res.isSSE = true
+		}
+
+		if f.Type.Implements(tItemStream) {
+			res.isItems = true
+		}
+
 		if f.Tag.Get(TagResponse) == TagResponseDirect {
 			res.isDirect = true
 		}
@@ -103,6 +127,14 @@ func (op *Operation) parseResponse(t reflect.Type) *parsedRes {
 			panic("response Meta field cannot exist when Data field is goapi.DataStream")
 		}
 
+		if res.isSSE {
+			panic("response Meta field cannot exist when Data field is goapi.SSE")
+		}
+
+		if res.isItems {
+			panic("response Meta field cannot exist when Data field is goapi.Stream")
+		}
+
 		res.hasMeta = true
 		res.meta = f.Type
 	}
@@ -110,17 +142,41 @@ func (op *Operation) parseResponse(t reflect.Type) *parsedRes {
 	return res
 }
 
-func (s *parsedRes) write(w http.ResponseWriter, res reflect.Value) {
+// write the response, the body will be omitted if the request method is HEAD.
+func (s *parsedRes) write(w http.ResponseWriter, r *http.Request, res reflect.Value) {
+	noBody := r.Method == http.MethodHead
+
+	var enc *bodyEncoder
+
+	formatErr := s.hasErr && s.operation.group.router.ErrorFormatter != nil
+
+	if (s.hasErr || s.hasData) && !s.isStream && !s.isSSE && !s.isItems && !formatErr {
+		if enc = s.encoder(w, r); enc == nil {
+			return
+		}
+	}
+
 	if s.contentType != "" {
 		w.Header().Set("Content-Type", s.contentType)
 	}
 
 	if s.hasHeader {
-		h := res.FieldByName("Header")
-		for i := 0; i < h.NumField(); i++ {
-			f := h.Field(i)
-			w.Header().Set(toHeaderName(s.header.Field(i).Name), f.String())
-		}
+		s.writeHeader(w.Header(), res.FieldByName("Header"))
+	}
+
+	if formatErr {
+		s.operation.group.router.writeError(w, r, s.statusCode, res.FieldByName("Error").Interface())
+		return
+	}
+
+	if s.isSSE {
+		s.writeSSE(w, r, res.FieldByName("Data").Interface().(sseStream), noBody)
+		return
+	}
+
+	if s.isItems {
+		s.writeStream(w, r, res.FieldByName("Data").Interface().(itemStream), noBody)
+		return
 	}
 
 	if s.isStream {
@@ -130,11 +186,19 @@ func (s *parsedRes) write(w http.ResponseWriter, res reflect.Value) {
 
 		data := res.FieldByName("Data").Interface()
 
+		if closer, ok := data.(io.Closer); ok {
+			defer func() { _ = closer.Close() }()
+		}
+
+		if rs, ok := data.(io.ReadSeeker); ok && s.statusCode == http.StatusOK && servable(data, w.Header()) {
+			s.serveContent(w, r, rs, modTime(data))
+			return
+		}
+
 		w.WriteHeader(s.statusCode)
-		_, _ = io.Copy(w, data.(DataStream))
 
-		if closer, ok := data.(io.Closer); ok {
-			_ = closer.Close()
+		if !noBody {
+			_, _ = io.Copy(w, data.(DataStream))
 		}
 
 		return
@@ -144,41 +208,264 @@ func (s *parsedRes) write(w http.ResponseWriter, res reflect.Value) {
 
 	if s.isDirect {
 		data = res.FieldByName("Data").Interface()
-	} else {
-		var format openapi.ResponseFormat
+	} else if enc != nil {
+		data = s.operation.group.router.envelope().Wrap(r, s.envelopeParts(res))
+	}
 
-		if s.hasErr { //nolint: gocritic
-			format = openapi.ResponseFormatErr{
-				Error: res.FieldByName("Error").Interface(),
-			}
-		} else if s.hasMeta {
-			format = openapi.ResponseFormatMeta{
-				Data: res.FieldByName("Data").Interface(),
-				Meta: res.FieldByName("Meta").Interface(),
-			}
-		} else if s.hasData {
-			format = openapi.ResponseFormatData{
-				Data: res.FieldByName("Data").Interface(),
-			}
-		}
+	useETag := enc != nil && s.useETag()
+	etag := ""
 
-		data = format
+	if useETag {
+		etag = s.customETag(res, enc.contentType)
+		if etag != "" && notModified(w, r, etag) {
+			return
+		}
 	}
 
-	if s.hasErr || s.hasData {
-		b, err := json.Marshal(data)
+	if enc != nil {
+		buf := bytes.NewBuffer(nil)
+
+		err := enc.encoder.Encode(buf, data)
 		if err != nil {
 			panic(s.operation.path.path + " " + err.Error())
 		}
 
-		setJSONHeader(w)
+		if useETag && etag == "" && notModified(w, r, bodyETag(buf.Bytes())) {
+			return
+		}
+
+		if enc.contentType == openapi.ContentTypeJSON {
+			setJSONHeader(w)
+		} else {
+			w.Header().Set("Content-Type", enc.contentType)
+		}
+
+		w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
 		w.WriteHeader(s.statusCode)
-		_, _ = w.Write(b)
+
+		if !noBody {
+			_, _ = w.Write(buf.Bytes())
+		}
 	} else {
 		w.WriteHeader(s.statusCode)
 	}
 }
 
+// resHeader is a field of the Header struct of a response.
+type resHeader struct {
+	name      string
+	flatField *ff.FlattenedField
+}
+
+// parseResHeaders uses the same naming rules as the [InHeader] params.
+func parseResHeaders(t reflect.Type) []*resHeader {
+	list := []*resHeader{}
+
+	for _, flat := range ff.Parse(t).Fields {
+		list = append(list, &resHeader{
+			name:      tagName(flat.Field.Tag, toHeaderName(flat.Field.Name)),
+			flatField: flat,
+		})
+	}
+
+	return list
+}
+
+// writeHeader sets the header fields of the response, a nil pointer or an empty slice is omitted,
+// each item of a slice is a value of the header.
+func (s *parsedRes) writeHeader(header http.Header, h reflect.Value) {
+	for _, f := range s.headers {
+		v, ok := getField(f.flatField, h)
+		if !ok {
+			continue
+		}
+
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				continue
+			}
+
+			v = v.Elem()
+		}
+
+		header.Del(f.name)
+
+		if v.Kind() != reflect.Slice {
+			header.Add(f.name, s.headerValue(f, v))
+
+			continue
+		}
+
+		for i := 0; i < v.Len(); i++ {
+			header.Add(f.name, s.headerValue(f, v.Index(i)))
+		}
+	}
+}
+
+func (s *parsedRes) headerValue(f *resHeader, v reflect.Value) string {
+	str, err := toString(v)
+	if err != nil {
+		panic(fmt.Sprintf("%s failed to format response header `%s`: %s", s.operation.path.path, f.name, err))
+	}
+
+	return str
+}
+
+// getField is like [ff.FlattenedField.Get], but it returns false if an embedded struct pointer is nil.
+func getField(f *ff.FlattenedField, v reflect.Value) (reflect.Value, bool) {
+	for _, i := range f.Path {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return reflect.Value{}, false
+			}
+
+			v = v.Elem()
+		}
+
+		v = v.Field(i)
+	}
+
+	return v, true
+}
+
+func (s *parsedRes) envelopeParts(res reflect.Value) EnvelopeParts {
+	p := EnvelopeParts{Status: s.statusCode, HasMeta: s.hasMeta, IsError: s.hasErr}
+
+	if s.hasErr {
+		p.Error = res.FieldByName("Error").Interface()
+
+		return p
+	}
+
+	p.Data = res.FieldByName("Data").Interface()
+
+	if s.hasMeta {
+		p.Meta = res.FieldByName("Meta").Interface()
+	}
+
+	return p
+}
+
+// servable returns true if the [DataStream] data opts in to be written by [http.ServeContent],
+// the content is seeked to the start, so it must describe the whole content.
+func servable(data any, h http.Header) bool {
+	switch data.(type) {
+	case interface{ ModTime() time.Time }, interface{ Stat() (fs.FileInfo, error) }, interface{ Size() int64 }:
+		return true
+	}
+
+	return h.Get("ETag") != ""
+}
+
+// serveContent writes the data by [http.ServeContent], its 412 and 416 responses are written by the writeError.
+func (s *parsedRes) serveContent(w http.ResponseWriter, r *http.Request, rs io.ReadSeeker, mod time.Time) {
+	cw := &contentWriter{ResponseWriter: w}
+
+	http.ServeContent(cw, r, "", mod, rs)
+
+	var target, msg string
+
+	switch cw.failed {
+	case http.StatusPreconditionFailed:
+		target, msg = "If-Match", "precondition failed"
+		if r.Header.Get("If-Match") == "" {
+			target = "If-Unmodified-Since"
+		}
+
+	case http.StatusRequestedRangeNotSatisfiable:
+		target, msg = "Range", "range not satisfiable"
+
+	default:
+		return
+	}
+
+	w.Header().Del("X-Content-Type-Options")
+
+	s.operation.group.router.writeError(w, r, cw.failed, &openapi.Error{
+		Code:    openapi.CodeInvalidParam,
+		Message: msg,
+		Target:  target,
+	})
+}
+
+// contentWriter holds back the 412 and 416 responses of [http.ServeContent].
+type contentWriter struct {
+	http.ResponseWriter
+	failed int
+}
+
+func (w *contentWriter) WriteHeader(code int) {
+	if code == http.StatusPreconditionFailed || code == http.StatusRequestedRangeNotSatisfiable {
+		w.failed = code
+		return
+	}
+
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *contentWriter) Write(b []byte) (int, error) {
+	if w.failed != 0 {
+		return len(b), nil
+	}
+
+	return w.ResponseWriter.Write(b)
+}
+
+// ReadFrom keeps the optimization of the [io.ReaderFrom] of the underlying writer, such as sendfile.
+func (w *contentWriter) ReadFrom(r io.Reader) (int64, error) {
+	if w.failed != 0 {
+		return io.Copy(io.Discard, r)
+	}
+
+	return io.Copy(w.ResponseWriter, r)
+}
+
+func (w *contentWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// modTime returns the modification time of the data of a [DataStream], zero if it's unknown.
+func modTime(data any) time.Time {
+	switch d := data.(type) {
+	case interface{ ModTime() time.Time }:
+		return d.ModTime()
+
+	case interface{ Stat() (fs.FileInfo, error) }:
+		if info, err := d.Stat(); err == nil {
+			return info.ModTime()
+		}
+	}
+
+	return time.Time{}
+}
+
+// encoder negotiates the encoder by the Accept header of the request,
+// it responds 406 and returns nil if none is acceptable and the response isn't an error.
+// The response varies by the Accept header when there are more than one encoders.
+func (s *parsedRes) encoder(w http.ResponseWriter, r *http.Request) *bodyEncoder {
+	router := s.operation.group.router
+
+	if len(*router.encoders.Load()) > 1 {
+		addVary(w.Header(), "Accept")
+	}
+
+	if enc := router.encoder(r.Header.Get("Accept")); enc != nil {
+		return enc
+	}
+
+	if s.hasErr {
+		return (*router.encoders.Load())[0]
+	}
+
+	router.writeError(w, r, http.StatusNotAcceptable, &openapi.Error{
+		Code:    openapi.CodeNotAcceptable,
+		Message: fmt.Sprintf("not acceptable: %s", r.Header.Get("Accept")),
+		Target:  "Accept",
+	})
+
+	return nil
+}
+
 func setJSONHeader(w http.ResponseWriter) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 }
@@ -0,0 +1,161 @@
+package goapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/NaturalSelectionLabs/goapi/lib/openapi"
+	"github.com/NaturalSelectionLabs/jschema"
+)
+
+// Event is a server-sent event of the [SSE].
+type Event[T any] struct {
+	// ID is the "id" field of the event, the client sends the last one as the Last-Event-ID header
+	// when it reconnects. It's omitted if it's empty.
+	ID string
+
+	// Event is the "event" field of the event, it's omitted if it's empty.
+	Event string
+
+	// Data is encoded as json for the "data" field of the event, a string is written as it is.
+	Data T
+
+	// Retry is the "retry" field of the event, it's omitted if it's zero.
+	Retry time.Duration
+}
+
+// SSE is a flag for the server-sent events response body.
+// When Data field in the response struct is of this type, each event received from it will be written
+// as the "text/event-stream" and flushed immediately. The response ends when the channel is closed or
+// the request context is canceled, the sender should also stop on the context cancellation.
+// To resume the events, use a [InHeader] param field named LastEventID to get the Last-Event-ID header.
+type SSE[T any] <-chan Event[T]
+
+// sseStream is implemented by all the [SSE] types.
+type sseStream interface {
+	// sseData returns the type of the Data of the events.
+	sseData() reflect.Type
+
+	// writeEvents blocks until the channel is closed or the ctx is canceled.
+	writeEvents(ctx context.Context, w http.ResponseWriter) error
+}
+
+var tSSEStream = reflect.TypeOf((*sseStream)(nil)).Elem()
+
+func (s SSE[T]) sseData() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+func (s SSE[T]) writeEvents(ctx context.Context, w http.ResponseWriter) error {
+	rc := http.NewResponseController(w)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case e, ok := <-s:
+			if !ok {
+				return nil
+			}
+
+			b, err := e.encode()
+			if err != nil {
+				return err
+			}
+
+			if _, err := w.Write(b); err != nil {
+				return nil //nolint: nilerr
+			}
+
+			_ = rc.Flush()
+		}
+	}
+}
+
+// encode the event with the framing of the "text/event-stream".
+func (e Event[T]) encode() ([]byte, error) {
+	buf := bytes.NewBuffer(nil)
+
+	if e.ID != "" {
+		fmt.Fprintf(buf, "id: %s\n", singleLine(e.ID))
+	}
+
+	if e.Event != "" {
+		fmt.Fprintf(buf, "event: %s\n", singleLine(e.Event))
+	}
+
+	if e.Retry > 0 {
+		fmt.Fprintf(buf, "retry: %d\n", e.Retry.Milliseconds())
+	}
+
+	var data string
+
+	if s, ok := any(e.Data).(string); ok {
+		data = s
+	} else {
+		b, err := json.Marshal(e.Data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode event data: %w", err)
+		}
+
+		data = string(b)
+	}
+
+	// Every line break of the "text/event-stream" ends a line, including a lone "\r".
+	data = strings.NewReplacer("\r\n", "\n", "\r", "\n").Replace(data)
+
+	for _, line := range strings.Split(data, "\n") {
+		fmt.Fprintf(buf, "data: %s\n", line)
+	}
+
+	buf.WriteString("\n")
+
+	return buf.Bytes(), nil
+}
+
+// singleLine removes the line breaks that would break the framing of the event.
+func singleLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
+
+// writeSSE writes the events of the Data of the response.
+func (s *parsedRes) writeSSE(w http.ResponseWriter, r *http.Request, data sseStream, noBody bool) {
+	w.Header().Set("Content-Type", openapi.ContentTypeSSE)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(s.statusCode)
+
+	if noBody || reflect.ValueOf(data).IsNil() {
+		return
+	}
+
+	_ = http.NewResponseController(w).Flush()
+
+	err := data.writeEvents(r.Context(), w)
+	if err != nil {
+		panic(s.operation.path.path + " " + err.Error())
+	}
+}
+
+// sseSchema documents the fields of an event, the data is the schema of the Data of the events.
+func sseSchema(s jschema.Schemas, t reflect.Type) *jschema.Schema {
+	data := s.DefineT(reflect.New(t).Elem().Interface().(sseStream).sseData())
+
+	return &jschema.Schema{
+		Type:        jschema.TypeObject,
+		Description: "Each event of the stream, the data is encoded as json except that a string is written as it is.",
+		Properties: jschema.Properties{
+			"id":    {Type: jschema.TypeString},
+			"event": {Type: jschema.TypeString},
+			"data":  data,
+			"retry": {Type: jschema.TypeInteger, Description: "milliseconds"},
+		},
+		Required: []string{"data"},
+	}
+}
@@ -0,0 +1,230 @@
+package goapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/NaturalSelectionLabs/goapi/lib/openapi"
+	"github.com/NaturalSelectionLabs/jschema"
+)
+
+// Stream is a flag for the streaming response body of items.
+// When Data field in the response struct is of this type, the function will be called to produce the items,
+// each item passed to yield is encoded as a line of the "application/x-ndjson",
+// or an element of a json array if the request accepts "application/json" instead.
+// The yield blocks until the item is written, and it returns an error if the item can't be written,
+// such as the request is canceled, then the function should stop and return.
+// If the function returns an error, it's logged by [Router.Logger] and a trailing error record is written:
+//
+//	{"error":{"code":"internal_error","message":"internal server error"}}
+//
+// Use [StreamChan] to create a Stream from a channel.
+type Stream[T any] func(yield func(item T) error) error
+
+// StreamChan returns a [Stream] of the items received from the ch until it's closed.
+func StreamChan[T any](ch <-chan T) Stream[T] {
+	return func(yield func(item T) error) error {
+		for item := range ch {
+			if err := yield(item); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+}
+
+// streamFlushInterval is the max delay of the written items of a [Stream] before they are flushed,
+// the items written within the interval are flushed together.
+const streamFlushInterval = 100 * time.Millisecond
+
+// itemStream is implemented by all the [Stream] types.
+type itemStream interface {
+	// streamItem returns the type of the items.
+	streamItem() reflect.Type
+
+	// run the stream, write is called for each item.
+	run(write func(item any) error) error
+}
+
+var tItemStream = reflect.TypeOf((*itemStream)(nil)).Elem()
+
+func (s Stream[T]) streamItem() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+func (s Stream[T]) run(write func(item any) error) error {
+	return s(func(item T) error {
+		return write(item)
+	})
+}
+
+// streamWriter writes the items of a [Stream] as the ndjson or the json array.
+type streamWriter struct {
+	ctx   context.Context
+	w     http.ResponseWriter
+	rc    *http.ResponseController
+	array bool
+
+	// mu guards the fields below, the timer flushes the pending items from another goroutine.
+	mu        sync.Mutex
+	count     int
+	lastFlush time.Time
+	timer     *time.Timer
+	ended     bool
+}
+
+func (sw *streamWriter) write(item any) error {
+	if err := sw.ctx.Err(); err != nil {
+		return err
+	}
+
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	b, err := json.Marshal(item)
+	if err != nil {
+		return fmt.Errorf("failed to encode stream item: %w", err)
+	}
+
+	if sw.array {
+		if sw.count == 0 {
+			b = append([]byte("["), b...)
+		} else {
+			b = append([]byte(","), b...)
+		}
+	} else {
+		b = append(b, '\n')
+	}
+
+	sw.count++
+
+	if _, err := sw.w.Write(b); err != nil {
+		return err
+	}
+
+	if wait := streamFlushInterval - time.Since(sw.lastFlush); wait <= 0 {
+		sw.flush()
+	} else if sw.timer == nil {
+		sw.timer = time.AfterFunc(wait, func() {
+			sw.mu.Lock()
+			defer sw.mu.Unlock()
+
+			if !sw.ended {
+				sw.flush()
+			}
+		})
+	}
+
+	return nil
+}
+
+// flush the written items, the pending timer is canceled. It must be called with the mu locked.
+func (sw *streamWriter) flush() {
+	if sw.timer != nil {
+		sw.timer.Stop()
+		sw.timer = nil
+	}
+
+	sw.lastFlush = time.Now()
+	_ = sw.rc.Flush()
+}
+
+// end writes the closing of the json array and flushes the rest of the stream,
+// nothing is written after it returns.
+func (sw *streamWriter) end() {
+	sw.mu.Lock()
+	defer sw.mu.Unlock()
+
+	if sw.array {
+		if sw.count == 0 {
+			_, _ = sw.w.Write([]byte("[]"))
+		} else {
+			_, _ = sw.w.Write([]byte("]"))
+		}
+	}
+
+	sw.flush()
+	sw.ended = true
+}
+
+// writeStream writes the items of the Data of the response, the content type is negotiated by the Accept header.
+func (s *parsedRes) writeStream(w http.ResponseWriter, r *http.Request, data itemStream, noBody bool) {
+	router := s.operation.group.router
+
+	types := []string{openapi.ContentTypeNDJSON, openapi.ContentTypeJSON}
+
+	addVary(w.Header(), "Accept")
+
+	i := negotiate(r.Header.Get("Accept"), types)
+	if i < 0 {
+		router.writeError(w, r, http.StatusNotAcceptable, &openapi.Error{
+			Code:    openapi.CodeNotAcceptable,
+			Message: fmt.Sprintf("not acceptable: %s", r.Header.Get("Accept")),
+			Target:  "Accept",
+		})
+
+		return
+	}
+
+	if types[i] == openapi.ContentTypeJSON {
+		setJSONHeader(w)
+	} else {
+		w.Header().Set("Content-Type", openapi.ContentTypeNDJSON)
+	}
+
+	w.WriteHeader(s.statusCode)
+
+	if noBody {
+		return
+	}
+
+	sw := &streamWriter{
+		ctx:   r.Context(),
+		w:     w,
+		rc:    http.NewResponseController(w),
+		array: types[i] == openapi.ContentTypeJSON,
+	}
+
+	if !reflect.ValueOf(data).IsNil() {
+		err := data.run(sw.write)
+		if err != nil && r.Context().Err() == nil {
+			_ = sw.write(router.envelope().Wrap(r, EnvelopeParts{
+				Status:  http.StatusInternalServerError,
+				Error:   router.internalError(r, err),
+				IsError: true,
+			}))
+		}
+	}
+
+	sw.end()
+}
+
+// streamContent documents the items of the stream for each content type.
+func (r *Router) streamContent(s jschema.Schemas, t reflect.Type) *openapi.Content {
+	item := s.DefineT(reflect.New(t).Elem().Interface().(itemStream).streamItem())
+
+	record := &jschema.Schema{
+		Description: "An item, or the trailing error record if the stream fails.",
+		AnyOf: []*jschema.Schema{item, r.envelope().Schema(EnvelopeSchemas{
+			Error: s.DefineT(tOpenAPIError),
+		})},
+	}
+
+	return &openapi.Content{
+		openapi.ContentTypeNDJSON: &openapi.Schema{
+			Schema: record,
+		},
+		openapi.ContentTypeJSON: &openapi.Schema{
+			Schema: &jschema.Schema{
+				Type:  jschema.TypeArray,
+				Items: record,
+			},
+		},
+	}
+}
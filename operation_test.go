@@ -1,9 +1,19 @@
 package goapi_test
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime/multipart"
 	"net/http"
+	"net/textproto"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -11,6 +21,8 @@ import (
 	"github.com/NaturalSelectionLabs/goapi/lib/middlewares"
 	"github.com/NaturalSelectionLabs/goapi/lib/middlewares/calm"
 	"github.com/NaturalSelectionLabs/goapi/lib/openapi"
+	"github.com/NaturalSelectionLabs/goapi/lib/websocket"
+	"github.com/NaturalSelectionLabs/jschema"
 	"github.com/ysmood/got"
 )
 
@@ -277,3 +289,846 @@ func TestOperation(t *testing.T) {
 	g.Gt(res.Bytes().Len(), 1000)
 	g.Eq(res.Header.Get("Content-Type"), "image/png")
 }
+
+type authMiddleware struct{}
+
+func (authMiddleware) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			middlewares.ResponseError(w, http.StatusUnauthorized, &openapi.Error{Code: openapi.CodeInvalidParam})
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func (authMiddleware) OpenAPI(doc *openapi.Operation) {
+	doc.Security = []map[string][]string{{"auth": {}}}
+	doc.Responses[openapi.StatusUnauthorized] = openapi.Response{Description: "Unauthorized"}
+}
+
+func TestOperationUse(t *testing.T) {
+	g := got.T(t)
+
+	r := goapi.New()
+
+	var order []string
+
+	trace := func(name string) middlewares.Middleware {
+		return middlewares.Func(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				order = append(order, name)
+				next.ServeHTTP(w, r)
+			})
+		})
+	}
+
+	r.GET("/private/{id}", func(p struct {
+		goapi.InURL
+		ID string
+	}) resOK {
+		order = append(order, "handler")
+		return resOK{Data: p.ID}
+	}).Use(trace("a"), authMiddleware{}).Use(trace("b"))
+
+	r.GET("/public", func() resOK { return resOK{Data: "ok"} })
+
+	r.GET("/override", func(w http.ResponseWriter, r *http.Request) {
+		order = append(order, "override")
+	}).Use(trace("c"))
+
+	tr := g.Serve()
+	tr.Mux.Handle("/", r.Server())
+
+	g.Eq(g.Req("", tr.URL("/private/1")).StatusCode, http.StatusUnauthorized)
+	g.Eq(order, []string{"a"})
+
+	g.Eq(g.Req("", tr.URL("/private/1"), http.Header{"Authorization": {"token"}}).JSON(), map[string]any{"data": "1"})
+	g.Eq(order, []string{"a", "a", "b", "handler"})
+
+	g.Eq(g.Req("", tr.URL("/public")).StatusCode, http.StatusOK)
+	g.Eq(g.Req(http.MethodPost, tr.URL("/private/1")).StatusCode, http.StatusMethodNotAllowed)
+	g.Eq(order, []string{"a", "a", "b", "handler"})
+
+	g.Req("", tr.URL("/override"))
+	g.Eq(order, []string{"a", "a", "b", "handler", "c", "override"})
+
+	doc := r.OpenAPI().Paths["/private/{id}"][openapi.GET]
+	g.Eq(doc.Security, []map[string][]string{{"auth": {}}})
+	g.Eq(doc.Responses[openapi.StatusUnauthorized].Description, "Unauthorized")
+	g.Nil(r.OpenAPI().Paths["/public"][openapi.GET].Security)
+}
+
+func TestOperationHandler(t *testing.T) {
+	g := got.T(t)
+
+	r := goapi.New()
+
+	op := r.GET("/users/{id}", func(p struct {
+		goapi.InURL
+		ID string
+	}) resOK {
+		return resOK{Data: p.ID}
+	})
+
+	notFound := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+
+	tr := g.Serve()
+	tr.Mux.Handle("/", op.Handler(notFound))
+
+	g.Eq(g.Req("", tr.URL("/users/1")).JSON(), map[string]any{"data": "1"})
+	g.Eq(g.Req(http.MethodPost, tr.URL("/users/1")).StatusCode, http.StatusTeapot)
+	g.Eq(g.Req("", tr.URL("/items/1")).StatusCode, http.StatusTeapot)
+}
+
+func TestForm(t *testing.T) {
+	g := got.T(t)
+
+	r := goapi.New()
+
+	r.POST("/form", func(p struct {
+		goapi.InForm
+		Name string
+	}) resOK {
+		return resOK{Data: p.Name}
+	})
+
+	tr := g.Serve()
+	tr.Mux.Handle("/", r.Server())
+
+	res, err := http.Post(tr.URL("/form"), "application/x-www-form-urlencoded; charset=utf-8",
+		strings.NewReader("name=a%20b"))
+	g.E(err)
+	g.Eq(g.Read(res.Body).String(), `{"data":"a b"}`)
+
+	res, err = http.Post(tr.URL("/form"), "application/json", strings.NewReader(`{"name":"a"}`))
+	g.E(err)
+	g.Eq(res.StatusCode, http.StatusUnsupportedMediaType)
+
+	res, err = http.Post(tr.URL("/form"), "application/x-www-form-urlencoded",
+		strings.NewReader("name="+strings.Repeat("a", 10<<20)))
+	g.E(err)
+	g.Eq(res.StatusCode, http.StatusBadRequest)
+	g.Has(g.Read(res.Body).String(), "request body too large")
+}
+
+type neverEnding struct{}
+
+func (neverEnding) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 'a'
+	}
+
+	return len(p), nil
+}
+
+type countReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	atomic.AddInt64(&c.n, int64(n))
+
+	return n, err
+}
+
+func TestMultipart(t *testing.T) {
+	g := got.T(t)
+
+	r := goapi.New()
+
+	r.POST("/upload", func(p struct {
+		goapi.InMultipart
+		Title  string
+		Avatar goapi.File `maxSize:"10" accept:"image/*"`
+		Docs   []goapi.File
+		Extra  *goapi.File
+	}) resOK {
+		b, _ := io.ReadAll(p.Avatar)
+		names := []string{}
+
+		for _, d := range p.Docs {
+			names = append(names, d.Filename)
+		}
+
+		return resOK{Data: fmt.Sprintf("%s %s %s %d %s %v %v",
+			p.Title, p.Avatar.Filename, p.Avatar.ContentType, p.Avatar.Size, b, names, p.Extra == nil)}
+	})
+
+	tr := g.Serve()
+	tr.Mux.Handle("/", r.Server())
+
+	type part struct {
+		name, filename, contentType, data string
+	}
+
+	upload := func(parts ...part) (int, string) {
+		buf := bytes.NewBuffer(nil)
+		w := multipart.NewWriter(buf)
+
+		for _, p := range parts {
+			if p.filename == "" {
+				g.E(w.WriteField(p.name, p.data))
+				continue
+			}
+
+			h := textproto.MIMEHeader{}
+			h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`, p.name, p.filename))
+			h.Set("Content-Type", p.contentType)
+			pw, err := w.CreatePart(h)
+			g.E(err)
+			g.E(pw.Write([]byte(p.data)))
+		}
+
+		g.E(w.Close())
+
+		res, err := http.Post(tr.URL("/upload"), w.FormDataContentType(), buf)
+		g.E(err)
+
+		return res.StatusCode, g.Read(res.Body).String()
+	}
+
+	code, body := upload(
+		part{name: "title", data: "hi"},
+		part{name: "avatar", filename: "a.png", contentType: "image/png", data: "png"},
+		part{name: "docs", filename: "a.txt", contentType: "text/plain", data: "a"},
+		part{name: "docs", filename: "b.txt", contentType: "text/plain", data: "b"},
+	)
+	g.Eq(code, http.StatusOK)
+	g.Eq(body, `{"data":"hi a.png image/png 3 png [a.txt b.txt] true"}`)
+
+	code, body = upload(part{name: "title", data: "hi"})
+	g.Eq(code, http.StatusBadRequest)
+	g.Has(body, "missing multipart file `avatar`")
+
+	_, body = upload(
+		part{name: "title", data: "hi"},
+		part{name: "avatar", filename: "a.png", contentType: "image/png", data: "01234567890"},
+	)
+	g.Has(body, "multipart file `avatar` exceeds the max size 10 bytes")
+
+	code, body = upload(
+		part{name: "title", data: "hi"},
+		part{name: "avatar", filename: "a.png", contentType: "image/png", data: "png"},
+		part{name: "docs", filename: "big.txt", contentType: "text/plain", data: strings.Repeat("a", 40<<20)},
+	)
+	g.Eq(code, http.StatusOK)
+	g.Eq(body, `{"data":"hi a.png image/png 3 png [big.txt] true"}`)
+
+	{
+		buf := bytes.NewBuffer(nil)
+		w := multipart.NewWriter(buf)
+		h := textproto.MIMEHeader{}
+		h.Set("Content-Disposition", `form-data; name="avatar"; filename="a.png"`)
+		h.Set("Content-Type", "image/png")
+		_, err := w.CreatePart(h)
+		g.E(err)
+
+		sent := &countReader{r: io.LimitReader(neverEnding{}, 1<<30)}
+
+		res, err := http.Post(tr.URL("/upload"), w.FormDataContentType(), io.MultiReader(buf, sent))
+		g.E(err)
+		g.Eq(res.StatusCode, http.StatusBadRequest)
+		g.Has(g.Read(res.Body).String(), "multipart file `avatar` exceeds the max size 10 bytes")
+		g.Lt(atomic.LoadInt64(&sent.n), 10<<20)
+	}
+
+	_, body = upload(
+		part{name: "title", data: "hi"},
+		part{name: "avatar", filename: "a.txt", contentType: "text/plain", data: "a"},
+	)
+	g.Has(body, "multipart file `avatar` has unaccepted content type `text/plain`")
+
+	_, body = upload(part{name: "avatar", filename: "a.png", contentType: "image/png", data: "png"})
+	g.Has(body, "missing multipart field `title`")
+
+	res, err := http.Post(tr.URL("/upload"), "application/json", strings.NewReader(`{"title":"hi"}`))
+	g.E(err)
+	g.Eq(res.StatusCode, http.StatusUnsupportedMediaType)
+
+	res, err = http.Post(tr.URL("/upload"), "multipart/form-data", strings.NewReader("x"))
+	g.E(err)
+	g.Eq(res.StatusCode, http.StatusBadRequest)
+	g.Has(g.Read(res.Body).String(), "failed to parse multipart body")
+
+	doc := r.OpenAPI().Paths["/upload"][openapi.POST].RequestBody
+	scm := (*doc.Content)[openapi.ContentTypeMultipart].Schema
+	g.Eq(scm.Properties["avatar"].Format, "binary")
+	g.Eq(scm.Properties["docs"].Items.Format, "binary")
+	g.Eq(scm.Properties["title"].Type, jschema.TypeString)
+	g.Eq(scm.Required, jschema.Required{"title", "avatar"})
+}
+
+func TestValidationDetails(t *testing.T) {
+	g := got.T(t)
+
+	r := goapi.New()
+
+	r.POST("/users/{id}", func(struct {
+		goapi.InURL
+		ID    int    `min:"1"`
+		Email string `format:"email"`
+		Page  *int
+	}, struct {
+		goapi.InHeader
+		XToken string
+	}, struct {
+		Name string `json:"name" pattern:"^[a-z]+$"`
+		Tags []string
+	}) resOK {
+		return resOK{}
+	})
+
+	tr := g.Serve()
+	tr.Mux.Handle("/", r.Server())
+
+	res := g.Req(http.MethodPost, tr.URL("/users/0?email=x&page=a"), `{"name": "A"}`)
+	g.Eq(res.StatusCode, http.StatusBadRequest)
+
+	var body struct {
+		Error openapi.Error
+	}
+
+	g.E(json.Unmarshal(res.Bytes().Bytes(), &body))
+
+	details := []string{}
+	for _, d := range body.Error.Details {
+		details = append(details, fmt.Sprintf("%s %s %s", d.Code, d.Target, d.Message))
+	}
+
+	g.Eq(body.Error.Code, openapi.CodeInvalidParam)
+	g.Eq(details, []string{
+		"min path:id param `id` is invalid: Must be greater than or equal to 1",
+		"format query:email param `email` is invalid: Does not match format 'email'",
+		"type query:page failed to parse url path param `page`: can't parse `a` to expected value, " +
+			"invalid character 'a' looking for beginning of value",
+		"required header:x-token missing header `x-token`",
+		"type body:/Tags request body is invalid: Tags: Invalid type. Expected: array, given: null",
+		"pattern body:/name request body is invalid: name: Does not match pattern '^[a-z]+$'",
+	})
+
+	doc := r.OpenAPI().Paths["/users/{id}"][openapi.POST].Responses[openapi.StatusBadRequest]
+	g.Eq(doc.Description, "The request parameters are invalid, each invalid parameter is listed in the error details.")
+}
+
+type ResHeaderCommon struct {
+	RequestID string `json:"x-request-id"`
+}
+
+type resTypedHeader struct {
+	goapi.StatusNoContent
+
+	Header struct {
+		*ResHeaderCommon
+		RateLimit    int
+		LastModified time.Time
+		Code         openapi.Code
+		Link         []string
+		Retry        *int
+	}
+}
+
+func TestResponseHeader(t *testing.T) {
+	g := got.T(t)
+
+	r := goapi.New()
+
+	modified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	r.GET("/", func(p struct {
+		goapi.InURL
+		Full bool `default:"false"`
+	}) resTypedHeader {
+		res := resTypedHeader{}
+		res.Header.RateLimit = 10
+		res.Header.LastModified = modified
+		res.Header.Code = openapi.CodeMax
+
+		if p.Full {
+			res.Header.ResHeaderCommon = &ResHeaderCommon{RequestID: "id"}
+			res.Header.Link = []string{"a", "b"}
+			res.Header.Retry = ptr(3)
+		}
+
+		return res
+	})
+
+	tr := g.Serve()
+	tr.Mux.Handle("/", r.Server())
+
+	h := g.Req("", tr.URL("/")).Header
+	g.Eq(h.Get("Rate-Limit"), "10")
+	g.Eq(h.Get("Last-Modified"), "2024-01-02T03:04:05Z")
+	g.Eq(h.Get("Code"), "max")
+	g.Eq(h.Values("Link"), []string(nil))
+	g.Eq(h.Values("Retry"), []string(nil))
+	g.Eq(h.Values("X-Request-Id"), []string(nil))
+
+	h = g.Req("", tr.URL("/?full=true")).Header
+	g.Eq(h.Values("Link"), []string{"a", "b"})
+	g.Eq(h.Get("Retry"), "3")
+	g.Eq(h.Get("X-Request-Id"), "id")
+
+	doc := r.OpenAPI().Paths["/"][openapi.GET].Responses[openapi.StatusNoContent].Headers
+	g.Len(doc, 6)
+	g.True(doc["rate-limit"].Required)
+	g.True(doc["code"].Required)
+	g.False(doc["link"].Required)
+	g.False(doc["retry"].Required)
+	g.Eq(doc["link"].Schema.Type, jschema.TypeArray)
+	g.Eq(doc["last-modified"].Schema.Ref.ID, "Time")
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+type resSSE struct {
+	goapi.StatusOK
+	Data goapi.SSE[resUser]
+}
+
+type resTextSSE struct {
+	goapi.StatusOK
+	Data goapi.SSE[string]
+}
+
+type resUser struct {
+	Name string `json:"name"`
+}
+
+func TestSSE(t *testing.T) {
+	g := got.T(t)
+
+	r := goapi.New()
+
+	stopped := make(chan struct{})
+
+	r.GET("/events", func(ctx context.Context, p struct {
+		goapi.InHeader
+		LastEventID int `default:"0"`
+	}) resSSE {
+		ch := make(chan goapi.Event[resUser])
+
+		go func() {
+			defer close(ch)
+
+			for i := p.LastEventID + 1; i <= 3; i++ {
+				e := goapi.Event[resUser]{ID: fmt.Sprint(i), Data: resUser{Name: fmt.Sprint("u", i)}}
+				if i == 3 {
+					e.Event = "done"
+					e.Retry = time.Second
+				}
+
+				ch <- e
+			}
+		}()
+
+		return resSSE{Data: ch}
+	})
+
+	r.GET("/forever", func(ctx context.Context) resSSE {
+		ch := make(chan goapi.Event[resUser])
+
+		go func() {
+			defer close(stopped)
+
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case ch <- goapi.Event[resUser]{}:
+				}
+			}
+		}()
+
+		return resSSE{Data: ch}
+	})
+
+	r.GET("/text", func() resTextSSE {
+		ch := make(chan goapi.Event[string], 1)
+		ch <- goapi.Event[string]{Data: "a\r\nb\rc\nd"}
+		close(ch)
+
+		return resTextSSE{Data: ch}
+	})
+
+	tr := g.Serve()
+	tr.Mux.Handle("/", r.Server())
+
+	g.Eq(g.Req("", tr.URL("/text")).String(), "data: a\ndata: b\ndata: c\ndata: d\n\n")
+
+	res := g.Req("", tr.URL("/events"))
+	g.Eq(res.Header.Get("Content-Type"), "text/event-stream")
+	g.Eq(res.Header.Get("Cache-Control"), "no-cache")
+	g.Eq(res.String(), "id: 1\ndata: {\"name\":\"u1\"}\n\n"+
+		"id: 2\ndata: {\"name\":\"u2\"}\n\n"+
+		"id: 3\nevent: done\nretry: 1000\ndata: {\"name\":\"u3\"}\n\n")
+
+	res = g.Req("", tr.URL("/events"), http.Header{"Last-Event-ID": {"2"}})
+	g.Eq(res.String(), "id: 3\nevent: done\nretry: 1000\ndata: {\"name\":\"u3\"}\n\n")
+
+	ctx, cancel := context.WithCancel(g.Context())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tr.URL("/forever"), nil)
+	g.E(err)
+	resp, err := http.DefaultClient.Do(req)
+	g.E(err)
+	_, err = resp.Body.Read(make([]byte, 10))
+	g.E(err)
+	cancel()
+	<-stopped
+
+	doc := r.OpenAPI().Paths["/events"][openapi.GET].Responses[openapi.StatusOK]
+	scm := (*doc.Content)["text/event-stream"].Schema
+	g.Eq(scm.Properties["data"].Ref.ID, "resUser")
+	g.Eq(scm.Properties["retry"].Type, jschema.TypeInteger)
+}
+
+type resStream struct {
+	goapi.StatusOK
+	Data goapi.Stream[resUser]
+}
+
+func TestStream(t *testing.T) {
+	g := got.T(t)
+
+	r := goapi.New()
+
+	r.GET("/users", func(p struct {
+		goapi.InURL
+		Fail bool `default:"false"`
+	}) resStream {
+		return resStream{Data: func(yield func(resUser) error) error {
+			for _, name := range []string{"a", "b"} {
+				if err := yield(resUser{Name: name}); err != nil {
+					return err
+				}
+			}
+
+			if p.Fail {
+				return errors.New("db closed")
+			}
+
+			return nil
+		}}
+	})
+
+	r.GET("/chan", func() resStream {
+		ch := make(chan resUser, 1)
+		ch <- resUser{Name: "c"}
+		close(ch)
+
+		return resStream{Data: goapi.StreamChan(ch)}
+	})
+
+	r.GET("/empty", func() resStream {
+		return resStream{}
+	})
+
+	live := make(chan resUser)
+
+	r.GET("/live", func() resStream {
+		return resStream{Data: goapi.StreamChan(live)}
+	})
+
+	tr := g.Serve()
+	tr.Mux.Handle("/", r.Server())
+
+	res := g.Req("", tr.URL("/users"))
+	g.Eq(res.Header.Get("Content-Type"), "application/x-ndjson")
+	g.Eq(res.Header.Get("Vary"), "Accept")
+	g.Eq(res.String(), "{\"name\":\"a\"}\n{\"name\":\"b\"}\n")
+
+	g.Eq(g.Req("", tr.URL("/users?fail=true")).String(), "{\"name\":\"a\"}\n{\"name\":\"b\"}\n"+
+		"{\"error\":{\"code\":\"internal_error\",\"message\":\"internal server error\"}}\n")
+
+	g.Eq(g.Req("", tr.URL("/chan")).String(), "{\"name\":\"c\"}\n")
+
+	{
+		go func() {
+			live <- resUser{Name: "a"}
+			live <- resUser{Name: "b"}
+		}()
+
+		res, err := http.Get(tr.URL("/live"))
+		g.E(err)
+		defer func() { _ = res.Body.Close() }()
+
+		lines := make(chan string)
+
+		go func() {
+			s := bufio.NewScanner(res.Body)
+			for s.Scan() {
+				lines <- s.Text()
+			}
+			close(lines)
+		}()
+
+		// the items are flushed without waiting for the next one
+		for _, name := range []string{"a", "b"} {
+			select {
+			case line := <-lines:
+				g.Eq(line, `{"name":"`+name+`"}`)
+			case <-time.After(3 * time.Second):
+				g.Fatal("the item isn't flushed:", name)
+			}
+		}
+
+		close(live)
+
+		_, open := <-lines
+		g.False(open)
+	}
+
+	get := func(path, accept string) *http.Response {
+		req, err := http.NewRequest(http.MethodGet, tr.URL(path), nil)
+		g.E(err)
+		req.Header.Set("Accept", accept)
+		res, err := http.DefaultClient.Do(req)
+		g.E(err)
+
+		return res
+	}
+
+	arr := get("/users", "application/json")
+	g.Eq(arr.Header.Get("Content-Type"), "application/json; charset=utf-8")
+	g.Eq(g.Read(arr.Body).String(), `[{"name":"a"},{"name":"b"}]`)
+
+	g.Eq(g.Read(get("/empty", "application/json").Body).String(), `[]`)
+	g.Eq(get("/users", "text/html").StatusCode, http.StatusNotAcceptable)
+
+	doc := *r.OpenAPI().Paths["/users"][openapi.GET].Responses[openapi.StatusOK].Content
+	g.Eq(doc[openapi.ContentTypeNDJSON].Schema.AnyOf[0].Ref.ID, "resUser")
+	g.Eq(doc[openapi.ContentTypeJSON].Schema.Items.AnyOf[0].Ref.ID, "resUser")
+}
+
+type wsIn struct {
+	Text string `json:"text" pattern:"^[a-z]+$"`
+}
+
+type wsOut struct {
+	Room  string `json:"room"`
+	Reply string `json:"reply"`
+}
+
+func TestWebSocket(t *testing.T) {
+	g := got.T(t)
+
+	r := goapi.New()
+
+	log := bytes.NewBuffer(nil)
+	r.Router().Logger = slog.New(slog.NewTextHandler(log, nil))
+
+	r.WS("/rooms/{room}", func(ctx context.Context, p struct {
+		goapi.InURL
+		Room string `minLen:"2"`
+	}, ws goapi.WebSocket[wsIn, wsOut],
+	) error {
+		for msg := range ws.In {
+			switch msg.Text {
+			case "bye":
+				return nil
+			case "fail":
+				return errors.New("boom")
+			}
+
+			select {
+			case ws.Out <- wsOut{Room: p.Room, Reply: msg.Text}:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+
+		return nil
+	})
+
+	tr := g.Serve()
+	tr.Mux.Handle("/", r.Server())
+
+	url := "ws" + strings.TrimPrefix(tr.URL("/rooms/ab"), "http")
+
+	dial := func() *websocket.Conn {
+		conn, _, err := websocket.Dial(g.Context(), url, nil)
+		g.E(err)
+
+		return conn
+	}
+
+	send := func(conn *websocket.Conn, msg string) {
+		g.E(conn.WriteMessage(websocket.OpText, []byte(msg)))
+	}
+
+	closeErr := func(conn *websocket.Conn) *websocket.CloseError {
+		_, _, err := conn.ReadMessage()
+		ce := &websocket.CloseError{}
+		g.True(errors.As(err, &ce))
+
+		return ce
+	}
+
+	conn := dial()
+	send(conn, `{"text":"hi"}`)
+	op, msg, err := conn.ReadMessage()
+	g.E(err)
+	g.Eq(op, websocket.OpText)
+	g.Eq(string(msg), `{"room":"ab","reply":"hi"}`)
+	send(conn, `{"text":"bye"}`)
+	g.Eq(closeErr(conn).Code, websocket.CloseNormal)
+
+	conn = dial()
+	send(conn, `{"text":"Hi"}`)
+	ce := closeErr(conn)
+	g.Eq(ce.Code, websocket.CloseInvalidPayload)
+	g.Has(ce.Reason, "message is invalid: text: Does not match pattern")
+
+	conn = dial()
+	send(conn, `{"text":"fail"}`)
+	g.Eq(*closeErr(conn), websocket.CloseError{Code: websocket.CloseInternalError, Reason: "internal server error"})
+	g.Has(log.String(), "err=boom")
+
+	_, res, err := websocket.Dial(g.Context(), strings.Replace(url, "/ab", "/a", 1), nil)
+	g.Is(err, websocket.ErrBadHandshake)
+	g.Eq(res.StatusCode, http.StatusBadRequest)
+
+	_, res, err = websocket.Dial(g.Context(), url, http.Header{"Origin": {"http://example.com"}})
+	g.Is(err, websocket.ErrBadHandshake)
+	g.Eq(res.StatusCode, http.StatusForbidden)
+
+	_, _, err = websocket.Dial(g.Context(), url, http.Header{"Origin": {tr.URL()}})
+	g.E(err)
+
+	r.Router().CheckWebSocketOrigin = func(r *http.Request) bool {
+		return r.Header.Get("Origin") == "http://example.com"
+	}
+
+	_, _, err = websocket.Dial(g.Context(), url, http.Header{"Origin": {"http://example.com"}})
+	g.E(err)
+
+	g.Eq(g.Req("", tr.URL("/rooms/ab")).StatusCode, http.StatusBadRequest)
+
+	doc := r.OpenAPI().Paths["/rooms/{room}"][openapi.GET]
+	g.Eq(doc.WebSocket.Inbound.Ref.ID, "wsIn")
+	g.Eq(doc.WebSocket.Outbound.Ref.ID, "wsOut")
+	g.Eq(doc.Responses[openapi.StatusSwitchingProtocols].Description,
+		"The connection is upgraded to the WebSocket protocol.")
+	g.Eq(doc.Responses[openapi.StatusForbidden].Description, "The origin of the WebSocket handshake is not allowed.")
+
+	g.Eq(g.Panic(func() {
+		r.WS("/x", func() {})
+	}), "the last param of websocket handler must be a goapi.WebSocket")
+
+	g.Eq(g.Panic(func() {
+		r.POST("/x", func(goapi.WebSocket[wsIn, wsOut]) {})
+	}), "websocket handler must use GET method")
+
+	g.Eq(g.Panic(func() {
+		r.WS("/x", func(goapi.WebSocket[wsIn, wsOut]) int { return 0 })
+	}), "websocket handler can only return an error")
+}
+
+type modTimeReader struct {
+	*strings.Reader
+	modTime time.Time
+}
+
+func (r modTimeReader) ModTime() time.Time {
+	return r.modTime
+}
+
+type resVideo struct {
+	goapi.StatusOK
+	Header struct {
+		ETag *string `json:"etag"`
+	}
+	Data goapi.DataStream
+}
+
+func TestDataStreamRange(t *testing.T) {
+	g := got.T(t)
+
+	r := goapi.New()
+
+	modified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	r.GET("/video", func(p struct {
+		goapi.InURL
+		Tag bool `default:"false"`
+	}) resVideo {
+		res := resVideo{Data: modTimeReader{strings.NewReader("0123456789"), modified}}
+		if p.Tag {
+			res.Header.ETag = ptr(`"v1"`)
+		}
+
+		return res
+	})
+
+	r.GET("/consumed", func() resVideo {
+		data := strings.NewReader("0123456789")
+		_, _ = data.Seek(4, io.SeekStart)
+
+		return resVideo{Data: struct{ io.ReadSeeker }{data}}
+	})
+
+	tr := g.Serve()
+	tr.Mux.Handle("/", r.Server())
+
+	get := func(path string, header http.Header) (*http.Response, string) {
+		req, err := http.NewRequest(http.MethodGet, tr.URL(path), nil)
+		g.E(err)
+		req.Header = header
+		res, err := http.DefaultClient.Do(req)
+		g.E(err)
+
+		return res, g.Read(res.Body).String()
+	}
+
+	res, body := get("/video", http.Header{})
+	g.Eq(res.StatusCode, http.StatusOK)
+	g.Eq(body, "0123456789")
+	g.Eq(res.Header.Get("Accept-Ranges"), "bytes")
+	g.Eq(res.Header.Get("Last-Modified"), "Tue, 02 Jan 2024 03:04:05 GMT")
+
+	res, body = get("/video", http.Header{"Range": {"bytes=2-4"}})
+	g.Eq(res.StatusCode, http.StatusPartialContent)
+	g.Eq(body, "234")
+	g.Eq(res.Header.Get("Content-Range"), "bytes 2-4/10")
+
+	res, body = get("/video", http.Header{"Range": {"bytes=20-"}})
+	g.Eq(res.StatusCode, http.StatusRequestedRangeNotSatisfiable)
+	g.Eq(res.Header.Get("Content-Range"), "bytes */10")
+	g.Eq(res.Header.Get("Content-Type"), "application/json; charset=utf-8")
+	g.Eq(body, `{"error":{"code":"invalid_param","message":"range not satisfiable","target":"Range"}}`+"\n")
+
+	res, body = get("/video?tag=true", http.Header{"If-Match": {`"v0"`}})
+	g.Eq(res.StatusCode, http.StatusPreconditionFailed)
+	g.Eq(body, `{"error":{"code":"invalid_param","message":"precondition failed","target":"If-Match"}}`+"\n")
+
+	res, body = get("/consumed", http.Header{"Range": {"bytes=0-1"}})
+	g.Eq(res.StatusCode, http.StatusOK)
+	g.Eq(res.Header.Get("Accept-Ranges"), "")
+	g.Eq(body, "456789")
+
+	res, _ = get("/video", http.Header{"If-Modified-Since": {"Tue, 02 Jan 2024 03:04:05 GMT"}})
+	g.Eq(res.StatusCode, http.StatusNotModified)
+
+	res, body = get("/video?tag=true", http.Header{"Range": {"bytes=2-4"}, "If-Range": {`"v0"`}})
+	g.Eq(res.StatusCode, http.StatusOK)
+	g.Eq(body, "0123456789")
+
+	res, body = get("/video?tag=true", http.Header{"Range": {"bytes=2-4"}, "If-Range": {`"v1"`}})
+	g.Eq(res.StatusCode, http.StatusPartialContent)
+	g.Eq(body, "234")
+
+	res, _ = get("/video?tag=true", http.Header{"If-None-Match": {`"v1"`}})
+	g.Eq(res.StatusCode, http.StatusNotModified)
+
+	doc := r.OpenAPI().Paths["/video"][openapi.GET].Responses
+	g.Eq(doc[openapi.StatusOK].Headers["Accept-Ranges"].Schema.Type, jschema.TypeString)
+	g.True(doc[openapi.StatusPartialContent].Headers["Content-Range"].Required)
+	g.Len(doc[openapi.StatusPartialContent].Headers, 3)
+}
@@ -0,0 +1,258 @@
+package goapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+
+	"github.com/NaturalSelectionLabs/goapi/lib/openapi"
+	"github.com/NaturalSelectionLabs/goapi/lib/websocket"
+	"github.com/NaturalSelectionLabs/jschema"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// WebSocket is the typed message channels of a WebSocket operation, check [Group.WS].
+// Each message is a json text message, the inbound messages are validated by the json schema of In.
+type WebSocket[In, Out any] struct {
+	// In receives the messages from the client, it's closed when the connection is closed.
+	In <-chan In
+
+	// Out sends the messages to the client. The handler should stop sending when the context
+	// of the request is canceled, it's canceled when the connection is closed.
+	Out chan<- Out
+}
+
+// webSocket is implemented by all the [WebSocket] types.
+type webSocket interface {
+	// messageTypes returns the types of the inbound and outbound messages.
+	messageTypes() (in, out reflect.Type)
+
+	// run pumps the messages between the conn and the channels until call returns or the conn is closed,
+	// call receives the channels as the [WebSocket].
+	run(ctx context.Context, cancel context.CancelFunc, conn *websocket.Conn,
+		validator *gojsonschema.Schema, call func(ws reflect.Value) error)
+}
+
+var tWebSocket = reflect.TypeOf((*webSocket)(nil)).Elem()
+
+func (WebSocket[In, Out]) messageTypes() (reflect.Type, reflect.Type) {
+	return reflect.TypeOf((*In)(nil)).Elem(), reflect.TypeOf((*Out)(nil)).Elem()
+}
+
+func (WebSocket[In, Out]) run(ctx context.Context, cancel context.CancelFunc, conn *websocket.Conn,
+	validator *gojsonschema.Schema, call func(ws reflect.Value) error,
+) {
+	in := make(chan In)
+	out := make(chan Out)
+
+	go func() {
+		defer close(in)
+		defer cancel()
+
+		for {
+			_, b, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+
+			var msg In
+
+			if err := decodeMessage(b, &msg, validator); err != nil {
+				_ = conn.Close(websocket.CloseInvalidPayload, err.Error())
+				return
+			}
+
+			select {
+			case in <- msg:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	written := make(chan struct{})
+
+	go func() {
+		defer close(written)
+
+		for {
+			select {
+			case msg := <-out:
+				b, err := json.Marshal(msg)
+				if err == nil {
+					err = conn.WriteMessage(websocket.OpText, b)
+				}
+
+				if err != nil {
+					cancel()
+					return
+				}
+
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	err := call(reflect.ValueOf(WebSocket[In, Out]{In: in, Out: out}))
+
+	cancel()
+	<-written
+
+	if err != nil {
+		_ = conn.Close(websocket.CloseInternalError, "internal server error")
+	} else {
+		_ = conn.Close(websocket.CloseNormal, "")
+	}
+}
+
+// decodeMessage decodes the json message b to v and validates it.
+func decodeMessage(b []byte, v any, validator *gojsonschema.Schema) error {
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("failed to parse message: %w", err)
+	}
+
+	res, _ := validator.Validate(gojsonschema.NewGoLoader(v))
+	if !res.Valid() {
+		errs := paramsError{}
+		errs.addResult(res, "message is invalid: ", jsonPointer)
+
+		return errs
+	}
+
+	return nil
+}
+
+// WS adds a WebSocket operation for the GET method, the handler receives a [WebSocket] as the last param,
+// the other params are bound before the connection is upgraded, such as:
+//
+//	func(ctx context.Context, p Params, ws goapi.WebSocket[In, Out]) error
+//
+// The connection is closed when the handler returns, if it returns an error, the connection is closed
+// with [websocket.CloseInternalError] and the error is logged by [Router.Logger]. An invalid inbound message
+// closes the connection with [websocket.CloseInvalidPayload] and the validation error as the reason.
+// A request that isn't a valid WebSocket handshake will be responded with 400, a cross-origin handshake
+// with 403 unless [Router.CheckWebSocketOrigin] allows it.
+// The message schemas are described by the "x-websocket" of the openapi operation.
+func (g *Group) WS(path string, handler OperationHandler) *Operation {
+	t := reflect.TypeOf(handler)
+
+	if t == nil || t.Kind() != reflect.Func || t.NumIn() == 0 || !t.In(t.NumIn()-1).Implements(tWebSocket) {
+		panic("the last param of websocket handler must be a goapi.WebSocket")
+	}
+
+	return g.Add(openapi.GET, path, handler)
+}
+
+// checkWS checks the signature of the WebSocket handler, it can only return an error.
+func (op *Operation) checkWS() *Operation {
+	if op.method != openapi.GET {
+		panic("websocket handler must use GET method")
+	}
+
+	for i, p := range op.params {
+		if p.isWebSocket && i != len(op.params)-1 {
+			panic("the last param of websocket handler must be a goapi.WebSocket")
+		}
+	}
+
+	switch {
+	case op.tHandler.NumOut() == 0:
+	case op.tHandler.NumOut() == 1 && op.tHandler.Out(0) == tError:
+		op.returnsErr = true
+	default:
+		panic("websocket handler can only return an error")
+	}
+
+	return op
+}
+
+// isWS returns true if the handler receives a [WebSocket].
+func (op *Operation) isWS() bool {
+	for _, p := range op.params {
+		if p.isWebSocket {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseWebSocketParam(s jschema.Schemas, t reflect.Type) *parsedParam {
+	in, _ := reflect.New(t).Elem().Interface().(webSocket).messageTypes()
+
+	validator, _ := gojsonschema.NewSchema(gojsonschema.NewGoLoader(s.ToStandAlone(s.DefineT(in))))
+
+	return &parsedParam{param: t, isWebSocket: true, bodyValidator: validator}
+}
+
+// handleWS upgrades the connection and calls the handler with the bound params,
+// the cancel cancels the context of the request.
+func (op *Operation) handleWS(w http.ResponseWriter, r *http.Request, params []reflect.Value,
+	cancel context.CancelFunc,
+) {
+	router := op.group.router
+
+	conn, err := (&websocket.Upgrader{CheckOrigin: router.CheckWebSocketOrigin}).Upgrade(w, r)
+	if errors.Is(err, websocket.ErrBadHandshake) {
+		router.writeError(w, r, http.StatusBadRequest, &openapi.Error{
+			Code:    openapi.CodeInvalidParam,
+			Message: "expect a websocket handshake request",
+			Target:  "Upgrade",
+		})
+
+		return
+	} else if errors.Is(err, websocket.ErrBadOrigin) {
+		router.writeError(w, r, http.StatusForbidden, &openapi.Error{
+			Code:    openapi.CodeInvalidParam,
+			Message: "the origin of the websocket handshake is not allowed",
+			Target:  "Origin",
+		})
+
+		return
+	} else if err != nil {
+		router.writeError(w, r, http.StatusInternalServerError, router.internalError(r, err))
+		return
+	}
+
+	p := op.params[len(op.params)-1]
+
+	reflect.New(p.param).Elem().Interface().(webSocket).run(r.Context(), cancel, conn, p.bodyValidator,
+		func(ws reflect.Value) error {
+			params[len(params)-1] = ws
+
+			outs := op.vHandler.Call(params)
+
+			if op.returnsErr && !outs[0].IsNil() {
+				err := outs[0].Interface().(error)
+				router.logError(r, err)
+
+				return err
+			}
+
+			return nil
+		})
+}
+
+// wsDoc documents the handshake responses and the message schemas of the WebSocket operation.
+func wsDoc(s jschema.Schemas, op *Operation, doc *openapi.Operation) {
+	in, out := reflect.New(op.params[len(op.params)-1].param).Elem().Interface().(webSocket).messageTypes()
+
+	doc.WebSocket = &openapi.WebSocket{
+		Inbound:  s.DefineT(in),
+		Outbound: s.DefineT(out),
+	}
+
+	doc.Responses[openapi.StatusSwitchingProtocols] = openapi.Response{
+		Description: "The connection is upgraded to the WebSocket protocol.",
+	}
+
+	doc.Responses[openapi.StatusBadRequest] = op.group.router.errorResDoc(s,
+		"The request is not a valid WebSocket handshake or the parameters are invalid.")
+
+	doc.Responses[openapi.StatusForbidden] = op.group.router.errorResDoc(s,
+		"The origin of the WebSocket handshake is not allowed.")
+}
@@ -1,38 +1,54 @@
 package goapi
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
 )
 
 // Path helps to handle openapi path pattern.
+// Besides the openapi style "{name}" parameter, a parameter can have a constraint,
+// such as "{id:int}", "{slug:[a-z-]+}", or be a catch-all as the last segment, such as "{file...}".
+// The value of the parameter must match the constraint, or the path won't match.
 type Path struct {
 	path  string
 	reg   *regexp.Regexp
 	names []string
+
+	params     []*pathParam
+	segments   []*pathSegment
+	wildcard   bool
+	normalized string
 }
 
-var regOpenAPIPath = regexp.MustCompile(`\{([^}]+)\}`)
+// Allow one level of nested braces for constraints like "{id:[0-9]{3}}".
+var regOpenAPIPath = regexp.MustCompile(`\{((?:[^{}]|\{[^{}]*\})+)\}`)
 
 // Converts OpenAPI style path to Go Regexp and returns path parameters.
 func newPath(path string, optionalSlash bool) (*Path, error) {
-	params := []string{}
+	p := &Path{path: path, names: []string{}, params: []*pathParam{}}
+
+	// Replace OpenAPI wildcards with Go RegExp named wildcards
+	toRegexp := func(path string) string {
+		return regOpenAPIPath.ReplaceAllStringFunc(path, func(m string) string {
+			param := parsePathParam(m[1 : len(m)-1])           // Strip outer braces from parameter
+			p.params = append(p.params, param)                 // Add param to list
+			p.names = append(p.names, param.name)              // Add param name to list
+			return "(?P<" + param.name + ">" + param.reg + ")" // Replace with Go Regexp named wildcard
+		})
+	}
 
 	var regexPath string
-	if strings.HasSuffix(path, "/*") {
-		regexPath = "^" + strings.ReplaceAll(path[:len(path)-2], "/", "\\/") + "(?:\\/(?P<path>.*))?$"
 
-		params = append(params, "path")
-	} else {
-		// Replace OpenAPI wildcards with Go RegExp named wildcards
-		regexPath = regOpenAPIPath.ReplaceAllStringFunc(path, func(m string) string {
-			param := m[1 : len(m)-1]          // Strip outer braces from parameter
-			params = append(params, param)    // Add param to list
-			return "(?P<" + param + ">[^/]+)" // Replace with Go Regexp named wildcard
-		})
+	p.wildcard = strings.HasSuffix(path, "/*")
+
+	if p.wildcard {
+		regexPath = "^" + strings.ReplaceAll(toRegexp(path[:len(path)-2]), "/", "\\/") + "(?:\\/(?P<path>.*))?$"
 
+		p.names = append(p.names, "path")
+	} else {
 		// Make sure the path starts with a "^", ends with a "$", and escape slashes
-		regexPath = "^" + strings.ReplaceAll(regexPath, "/", "\\/") + "$"
+		regexPath = "^" + strings.ReplaceAll(toRegexp(path), "/", "\\/") + "$"
 
 		if optionalSlash && strings.HasSuffix(regexPath, "\\/$") {
 			regexPath = regexPath[:len(regexPath)-3] + "\\/?$"
@@ -45,7 +61,16 @@ func newPath(path string, optionalSlash bool) (*Path, error) {
 		return nil, err
 	}
 
-	return &Path{path, r, params}, nil
+	p.reg = r
+
+	p.segments, err = parsePathSegments(path, p.wildcard)
+	if err != nil {
+		return nil, err
+	}
+
+	p.normalized = p.normalize()
+
+	return p, nil
 }
 
 func (p *Path) match(path string) map[string]string {
@@ -81,3 +106,228 @@ func (p *Path) contains(v string) bool {
 
 	return false
 }
+
+// param returns the parameter with the name, nil if not found.
+func (p *Path) param(name string) *pathParam {
+	for _, param := range p.params {
+		if param.name == name {
+			return param
+		}
+	}
+
+	return nil
+}
+
+// doc returns the path for openapi doc, the constraints of the parameters are removed,
+// such as "/users/{id:int}" becomes "/users/{id}".
+func (p *Path) doc() string {
+	return regOpenAPIPath.ReplaceAllStringFunc(p.path, func(m string) string {
+		return "{" + parsePathParam(m[1:len(m)-1]).name + "}"
+	})
+}
+
+// docTemplate returns the openapi path without the names of the parameters, such as "/users/{}".
+// OpenAPI treats two paths with the same template as the same path.
+func (p *Path) docTemplate() string {
+	return regOpenAPIPath.ReplaceAllString(p.path, "{}")
+}
+
+// normalize returns the path without the names of the parameters, such as "/users/{}".
+// Two paths with the same normalized path match exactly the same urls.
+func (p *Path) normalize() string {
+	list := []string{}
+	for _, s := range p.segments {
+		list = append(list, regOpenAPIPath.ReplaceAllStringFunc(s.raw, func(m string) string {
+			param := parsePathParam(m[1 : len(m)-1])
+			switch {
+			case param.rest:
+				return "{...}"
+			case param.constraint != "":
+				return "{:" + param.constraint + "}"
+			default:
+				return "{}"
+			}
+		}))
+	}
+
+	n := "/" + strings.Join(list, "/")
+
+	if p.wildcard {
+		n = strings.TrimSuffix(n, "/") + "/*"
+	}
+
+	return n
+}
+
+// shadows returns true if p is a wildcard or catch-all path and other is under the prefix of p.
+func (p *Path) shadows(other *Path) bool {
+	if p == other {
+		return false
+	}
+
+	var prefix string
+
+	switch {
+	case p.wildcard:
+		prefix = strings.TrimSuffix(p.normalized, "*")
+	case len(p.segments) > 0 && p.segments[len(p.segments)-1].rest:
+		prefix = strings.TrimSuffix(p.normalized, "{...}")
+	default:
+		return false
+	}
+
+	n := other.normalized
+
+	return strings.HasPrefix(n, prefix) || n+"/" == prefix
+}
+
+// pathParam is a parameter of a path, such as "{id}", "{id:int}", "{slug:[a-z-]+}" or "{file...}".
+type pathParam struct {
+	name string
+
+	// constraint is the text after the colon, it's either a key of [pathParamTypes] or a regexp.
+	constraint string
+
+	// reg is the regexp to match the value of the parameter.
+	reg string
+
+	// rest means the parameter matches the rest of the path, including slashes.
+	rest bool
+}
+
+// pathParamTypes are the predefined constraints for path parameters.
+var pathParamTypes = map[string]string{
+	"int":  `-?[0-9]+`,
+	"uuid": `[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`,
+}
+
+func parsePathParam(s string) *pathParam {
+	p := &pathParam{name: s, reg: "[^/]+"}
+
+	if strings.HasSuffix(s, "...") {
+		p.name = strings.TrimSuffix(s, "...")
+		p.rest = true
+		p.reg = ".+"
+
+		return p
+	}
+
+	if i := strings.Index(s, ":"); i >= 0 {
+		p.name, p.constraint = s[:i], s[i+1:]
+
+		if reg, has := pathParamTypes[p.constraint]; has {
+			p.reg = reg
+		} else {
+			p.reg = p.constraint
+		}
+	}
+
+	return p
+}
+
+// pattern returns the anchored regexp of the constraint, empty if there's no constraint.
+func (p *pathParam) pattern() string {
+	if p.constraint == "" {
+		return ""
+	}
+
+	return "^(?:" + p.reg + ")$"
+}
+
+// pathSegment is the pattern of the text between two slashes of a path.
+type pathSegment struct {
+	raw string
+
+	// param is the name of the parameter when the whole segment is a parameter without constraint, such as "{id}".
+	param string
+
+	// reg is used when the segment mixes text and parameters or the parameter has a constraint,
+	// such as "{name}.json" or "{id:int}".
+	reg *regexp.Regexp
+
+	// rest means the segment is a catch-all parameter that matches the rest of the path, such as "{file...}".
+	rest bool
+}
+
+func parsePathSegments(path string, wildcard bool) ([]*pathSegment, error) {
+	if wildcard {
+		path = path[:len(path)-2]
+
+		if path == "" {
+			return []*pathSegment{}, nil
+		}
+	}
+
+	list := []*pathSegment{}
+
+	raws := splitPath(path)
+
+	for i, raw := range raws {
+		s := &pathSegment{raw: raw}
+
+		ms := regOpenAPIPath.FindAllStringSubmatch(raw, -1)
+
+		for _, m := range ms {
+			if parsePathParam(m[1]).rest {
+				if m[0] != raw || i != len(raws)-1 || wildcard {
+					return nil, fmt.Errorf("catch-all parameter %s must be the last segment of the path: %s", m[0], path)
+				}
+
+				s.rest = true
+			}
+		}
+
+		if len(ms) == 1 && ms[0][0] == raw && !s.rest && parsePathParam(ms[0][1]).constraint == "" {
+			s.param = parsePathParam(ms[0][1]).name
+		} else if len(ms) > 0 {
+			reg, err := regexp.Compile("^" + regOpenAPIPath.ReplaceAllStringFunc(raw, func(m string) string {
+				param := parsePathParam(m[1 : len(m)-1])
+				return "(?P<" + param.name + ">" + param.reg + ")"
+			}) + "$")
+			if err != nil {
+				return nil, err
+			}
+
+			s.reg = reg
+		}
+
+		list = append(list, s)
+	}
+
+	return list, nil
+}
+
+func (s *pathSegment) isStatic() bool {
+	return s.param == "" && s.reg == nil
+}
+
+// match the segment and append the name value pairs of the parameters to params.
+func (s *pathSegment) match(v string, params *[]string) bool {
+	if v == "" {
+		return false
+	}
+
+	if s.reg == nil {
+		*params = append(*params, s.param, v)
+		return true
+	}
+
+	ms := s.reg.FindStringSubmatch(v)
+	if ms == nil {
+		return false
+	}
+
+	for i, name := range s.reg.SubexpNames() {
+		if i == 0 || name == "" {
+			continue
+		}
+
+		*params = append(*params, name, ms[i])
+	}
+
+	return true
+}
+
+func splitPath(path string) []string {
+	return strings.Split(strings.TrimPrefix(path, "/"), "/")
+}
@@ -25,3 +25,26 @@ func TestPath(t *testing.T) {
 
 	g.Eq(p.match("/a/x/y"), map[string]string{"*": "x/y"})
 }
+
+func TestPathConstraints(t *testing.T) {
+	g := got.T(t)
+
+	p, err := newPath("/a/{id:int}/{slug:[a-z-]+}/{code:[0-9]{3}}", false)
+	g.E(err)
+
+	g.Eq(p.names, []string{"id", "slug", "code"})
+	g.Eq(p.doc(), "/a/{id}/{slug}/{code}")
+	g.Eq(p.normalized, "/a/{:int}/{:[a-z-]+}/{:[0-9]{3}}")
+	g.Eq(p.match("/a/-1/x-y/123"), map[string]string{"id": "-1", "slug": "x-y", "code": "123"})
+	g.Nil(p.match("/a/x/x-y/123"))
+	g.Eq(p.param("code").pattern(), "^(?:[0-9]{3})$")
+
+	p, err = newPath("/files/{file...}", false)
+	g.E(err)
+
+	g.Eq(p.match("/files/a/b.txt"), map[string]string{"file": "a/b.txt"})
+	g.Nil(p.match("/files/"))
+
+	_, err = newPath("/files/{file...}/x", false)
+	g.Eq(err.Error(), "catch-all parameter {file...} must be the last segment of the path: /files/{file...}/x")
+}
@@ -4,7 +4,11 @@ package goapi
 import (
 	"context"
 	"fmt"
+	"log/slog"
 	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/NaturalSelectionLabs/goapi/lib/middlewares"
 	"github.com/NaturalSelectionLabs/goapi/lib/openapi"
@@ -14,12 +18,77 @@ import (
 
 // Router for routing http requests to handlers.
 // It implements the [middlewares.Middleware] interface.
+// It's safe to add or remove operations and middlewares while the router is serving,
+// the changes are applied by atomically swapping the snapshot of the routing table.
 type Router struct {
 	Schemas jschema.Schemas
 
-	middlewares []middlewares.Middleware
-	operations  []*Operation
-	sever       *http.Server
+	// AutoHEAD makes a HEAD request be handled by the GET operation of the same path without the response body,
+	// when there's no HEAD operation for the path.
+	AutoHEAD bool
+
+	// AutoOPTIONS makes an OPTIONS request be responded with the Allow header that lists the methods of the path,
+	// when there's no OPTIONS operation for the path.
+	AutoOPTIONS bool
+
+	// OpenAPIAutoMethods controls whether the operations created by [Router.AutoHEAD] and [Router.AutoOPTIONS]
+	// are included in the [Router.OpenAPI] doc. They are excluded by default.
+	OpenAPIAutoMethods bool
+
+	// StrictRoutes makes the registration panic when a route is partially shadowed by a wildcard route
+	// of the same method, such as "/files/{name}" and "/files/*".
+	StrictRoutes bool
+
+	// AutoETag adds a strong ETag computed from the encoded body to the 200 responses of GET and HEAD requests,
+	// a request with a matching If-None-Match header is responded with 304 without the body.
+	// The streaming responses are excluded. Use [Group.AutoETag] or [ETagger] to enable it for a part of the routes.
+	AutoETag bool
+
+	// ErrorFormatter formats all the error responses of the router, such as the 404, the 400 of invalid params,
+	// the panics recovered by calm, and the responses with the Error field.
+	// The default is [ErrorEnvelope], use [ProblemDetails] for the RFC 9457 format.
+	ErrorFormatter ErrorFormatter
+
+	// Envelope wraps the Data, Meta, and Error fields of the responses as the response body,
+	// the openapi doc uses the same envelope for the response schemas. The default is [DefaultEnvelope].
+	// The errors written by the router are also wrapped by it if the ErrorFormatter is nil.
+	Envelope Envelope
+
+	// CheckWebSocketOrigin returns false to reject the handshake of the [Group.WS] operations with 403,
+	// the default only allows the handshakes without the Origin header or from the same host.
+	CheckWebSocketOrigin func(r *http.Request) bool
+
+	// Logger logs the unmapped errors returned by the handlers, the client only gets a generic 500 error.
+	// The default is [slog.Default].
+	Logger *slog.Logger
+
+	// mu guards the registration, the Schemas, and the fields below it.
+	mu sync.Mutex
+
+	middlewares   atomic.Pointer[[]middlewares.Middleware]
+	decoders      atomic.Pointer[[]*bodyDecoder]
+	encoders      atomic.Pointer[[]*bodyEncoder]
+	errorMappings atomic.Pointer[[]*errorMapping]
+	operations    []*Operation
+	mounts        []*mount
+	sever         *http.Server
+
+	// parents are the routers that mount this router, it's guarded by the mountMu.
+	parents []*Router
+
+	serverOnce sync.Once
+	server     http.Handler
+}
+
+// mountMu guards the mount relations between the routers, it's locked before the mu of any router.
+// It serializes the registrations, so that the schema collisions of the mounted routers are checked
+// against a stable set of operations.
+var mountMu sync.Mutex
+
+// mount is a router mounted by [Group.Mount].
+type mount struct {
+	prefix string
+	router *Router
 }
 
 // New is a shortcut for:
@@ -37,24 +106,55 @@ func NewRouter() *Router {
 	s.HijackJSONRawMessage()
 	s.HijackBigInt()
 
-	return &Router{
-		middlewares: []middlewares.Middleware{},
-		Schemas:     s,
+	r := &Router{
+		Schemas: s,
 	}
+
+	r.middlewares.Store(&[]middlewares.Middleware{})
+	r.decoders.Store(&[]*bodyDecoder{{openapi.ContentTypeJSON, DecoderJSON}})
+	r.encoders.Store(&[]*bodyEncoder{{openapi.ContentTypeJSON, EncoderJSON}})
+	r.errorMappings.Store(&[]*errorMapping{})
+
+	return r
 }
 
 // ServerHandler with a 404 middleware at the end.
+// If the path matches some operations but the method doesn't, it responds 405 with the Allow header.
 func (r *Router) ServerHandler() http.Handler {
 	return r.Handler(http.HandlerFunc(func(w http.ResponseWriter, rq *http.Request) {
-		middlewares.ResponseError(w, http.StatusNotFound, &openapi.Error{
+		path := originalPath(rq)
+
+		if allow := r.allowedMethods(rq.URL.Path, r.AutoOPTIONS); len(allow) > 0 {
+			w.Header().Set("Allow", strings.Join(allow, ", "))
+			r.writeError(w, rq, http.StatusMethodNotAllowed, &openapi.Error{
+				Code:       openapi.CodeMethodNotAllowed,
+				Message:    fmt.Sprintf("method not allowed: %s %s", rq.Method, path),
+				Target:     path,
+				InnerError: allow,
+			})
+
+			return
+		}
+
+		r.writeError(w, rq, http.StatusNotFound, &openapi.Error{
 			Code:       openapi.CodeNotFound,
-			Message:    fmt.Sprintf("path not found: %s %s", rq.Method, rq.URL.Path),
-			Target:     rq.URL.Path,
-			InnerError: []any{rq.Method, rq.URL.Path},
+			Message:    fmt.Sprintf("path not found: %s %s", rq.Method, path),
+			Target:     path,
+			InnerError: []any{rq.Method, path},
 		})
 	}))
 }
 
+// ServeHTTP implements the [http.Handler] interface with the [Router.ServerHandler].
+// The handler is built on the first request and reused by the following ones.
+func (r *Router) ServeHTTP(w http.ResponseWriter, rq *http.Request) {
+	r.serverOnce.Do(func() {
+		r.server = r.ServerHandler()
+	})
+
+	r.server.ServeHTTP(w, rq)
+}
+
 // Start listen on addr with the [Router.ServerHandler].
 func (r *Router) Start(addr string) error {
 	r.sever = &http.Server{
@@ -72,13 +172,267 @@ func (r *Router) Shutdown(ctx context.Context) error {
 
 // Use a middleware to the router.
 func (r *Router) Use(middlewares ...middlewares.Middleware) {
-	r.middlewares = append(r.middlewares, middlewares...)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.use(middlewares...)
+}
+
+// use must be called with the lock held.
+func (r *Router) use(ms ...middlewares.Middleware) {
+	list := append([]middlewares.Middleware{}, r.chain()...)
+	list = append(list, ms...)
+	r.middlewares.Store(&list)
+}
+
+// chain returns the current snapshot of the middleware chain.
+func (r *Router) chain() []middlewares.Middleware {
+	if list := r.middlewares.Load(); list != nil {
+		return *list
+	}
+
+	return nil
+}
+
+// add the operation to the route tree at the end of the middleware chain, it must be called with the lock held.
+// Consecutive operations share the same tree, so that middlewares added between them
+// still only apply to the operations added after the middlewares.
+func (r *Router) add(op *Operation) {
+	r.checkConflict(op)
+
+	r.operations = append(r.operations, op)
+
+	var tree *routeTree
+	if list := r.chain(); len(list) > 0 {
+		tree, _ = list[len(list)-1].(*routeTree)
+	}
+
+	if tree == nil {
+		tree = newRouteTree(r)
+		r.use(tree)
+	}
+
+	tree.add(op)
+}
+
+// mount sub at the prefix, it panics if sub is r or contains r, or the schema names collide.
+func (r *Router) mount(prefix string, sub *Router) *mount {
+	mountMu.Lock()
+	defer mountMu.Unlock()
+
+	if sub == r || sub.contains(r) {
+		panic("can't mount a router into itself or the routers it mounts")
+	}
+
+	m := &mount{prefix: prefix, router: sub}
+
+	r.setMounts(append(r.mounts, m))
+	sub.parents = append(sub.parents, r)
+
+	if err := r.checkSchemas(); err != nil {
+		r.setMounts(r.mounts[:len(r.mounts)-1])
+		sub.parents = sub.parents[:len(sub.parents)-1]
+
+		panic(err)
+	}
+
+	return m
+}
+
+// setMounts must be called with the mountMu held.
+func (r *Router) setMounts(list []*mount) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.mounts = list
+}
+
+// contains returns true if other is mounted by r directly or indirectly, it must be called with the mountMu held.
+func (r *Router) contains(other *Router) bool {
+	for _, m := range r.mounts {
+		if m.router == other || m.router.contains(other) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// checkSchemas returns an error if a schema name collides between the routers mounted together with r,
+// it must be called with the mountMu held.
+func (r *Router) checkSchemas() error {
+	if len(r.parents) == 0 && len(r.mounts) == 0 {
+		return nil
+	}
+
+	for _, root := range r.roots() {
+		if err := root.collectSchemas("", schemaSet{}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// defineSchemas defines the schemas of the operations that are not defined yet, so that the collisions
+// of the mounted routers are detected at the registration instead of the doc generation.
+// It must be called with the lock held.
+func (r *Router) defineSchemas() {
+	for _, op := range r.operations {
+		if op.override == nil && !op.schemasDefined {
+			op.schemaRefs = schemaRefs(handlerDoc(r.Schemas, op))
+			op.schemasDefined = true
+		}
+	}
+}
+
+// usedSchemas returns the schemas referenced by the operations directly or indirectly,
+// the schemas left in the Schemas by a rejected operation are excluded. It must be called with the lock held.
+func (r *Router) usedSchemas() map[string]*jschema.Schema {
+	refs := []string{}
+	for _, op := range r.operations {
+		refs = append(refs, op.schemaRefs...)
+	}
+
+	return reachableSchemas(refs, r.Schemas.JSON())
+}
+
+// roots returns the routers that mount r directly or indirectly and are not mounted by others.
+func (r *Router) roots() []*Router {
+	if len(r.parents) == 0 {
+		return []*Router{r}
+	}
+
+	list := []*Router{}
+	for _, p := range r.parents {
+		list = append(list, p.roots()...)
+	}
+
+	return list
+}
+
+// collectSchemas adds the schemas of r and the routers it mounts to set, prefix is where r is mounted.
+func (r *Router) collectSchemas(prefix string, set schemaSet) error {
+	r.mu.Lock()
+	r.defineSchemas()
+	err := set.merge(prefix, r.usedSchemas())
+	r.mu.Unlock()
+
+	if err != nil {
+		return err
+	}
+
+	for _, m := range r.mounts {
+		if err := m.router.collectSchemas(prefix+m.prefix, set); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// remove the operation from the router, it must be called with the lock held.
+func (r *Router) remove(op *Operation) {
+	for i, o := range r.operations {
+		if o == op {
+			r.operations = append(r.operations[:i:i], r.operations[i+1:]...)
+			op.tree.remove(op)
+
+			return
+		}
+	}
+}
+
+// checkConflict panics if op matches the same urls as an existing operation of the same method,
+// or they share the same openapi path, which ignores the names and constraints of the parameters,
+// such as "/users/{id:int}" and "/users/{name}". Operations of different methods can share an openapi path
+// only if the names of the parameters are the same.
+func (r *Router) checkConflict(op *Operation) {
+	for _, o := range r.operations {
+		sameMethod := o.method == op.method
+
+		if sameMethod && o.path.normalized == op.path.normalized {
+			panic(fmt.Sprintf("route %s conflicts with route %s", op.describe(), o.describe()))
+		}
+
+		if o.path.docTemplate() == op.path.docTemplate() {
+			if sameMethod {
+				panic(fmt.Sprintf("route %s and route %s have the same openapi path %s",
+					op.describe(), o.describe(), op.path.doc()))
+			}
+
+			if o.path.doc() != op.path.doc() {
+				panic(fmt.Sprintf("route %s and route %s have the same openapi path %s, "+
+					"their path parameters must have the same names", op.describe(), o.describe(), o.path.doc()))
+			}
+		}
+
+		if !sameMethod {
+			continue
+		}
+
+		if r.StrictRoutes && (o.path.shadows(op.path) || op.path.shadows(o.path)) {
+			panic(fmt.Sprintf("route %s and route %s shadow each other", op.describe(), o.describe()))
+		}
+	}
+}
+
+// allowedMethods returns the methods of the operations that match the path,
+// including the ones enabled by [Router.AutoHEAD] and the OPTIONS if autoOPTIONS is true.
+func (r *Router) allowedMethods(path string, autoOPTIONS bool) []string {
+	set := map[openapi.Method]bool{}
+
+	for _, m := range r.chain() {
+		if tree, ok := m.(*routeTree); ok {
+			tree.methods(path, set)
+		}
+	}
+
+	if r.AutoHEAD && set[openapi.GET] {
+		set[openapi.HEAD] = true
+	}
+
+	if autoOPTIONS && len(set) > 0 {
+		set[openapi.OPTIONS] = true
+	}
+
+	list := []string{}
+
+	for _, m := range openapi.MethodValues() {
+		if set[m] {
+			list = append(list, m.String())
+		}
+	}
+
+	return list
 }
 
 // Handler implements the [middlewares.Middleware] interface.
 // It makes the router itself a middleware.
 func (r *Router) Handler(next http.Handler) http.Handler {
-	return middlewares.Chain(r.middlewares...).Handler(next)
+	type cache struct {
+		chain   *[]middlewares.Middleware
+		handler http.Handler
+	}
+
+	var last atomic.Pointer[cache]
+
+	return http.HandlerFunc(func(w http.ResponseWriter, rq *http.Request) {
+		chain := r.middlewares.Load()
+
+		// Only rebuild the handler when the middleware chain changes.
+		c := last.Load()
+		if c == nil || c.chain != chain {
+			c = &cache{chain, middlewares.Chain(*chain...).Handler(next)}
+			last.Store(c)
+		}
+
+		if r.ErrorFormatter != nil || r.Envelope != nil {
+			rq = middlewares.WithErrorFormatter(rq, r.errorFormatter())
+		}
+
+		c.handler.ServeHTTP(w, rq)
+	})
 }
 
 // Group creates a new group with the given prefix.
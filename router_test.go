@@ -2,11 +2,16 @@ package goapi_test
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"reflect"
+	"sync"
 	"testing"
 
 	"github.com/NaturalSelectionLabs/goapi"
 	"github.com/NaturalSelectionLabs/goapi/lib/middlewares"
+	"github.com/NaturalSelectionLabs/goapi/lib/openapi"
+	"github.com/NaturalSelectionLabs/jschema"
 	"github.com/ysmood/got"
 )
 
@@ -73,3 +78,285 @@ func setupRouter(g got.G, setup func(r *goapi.Group)) *got.Router {
 
 	return tr
 }
+
+func TestMethodNotAllowed(t *testing.T) {
+	g := got.T(t)
+
+	tr := setupRouter(g, func(r *goapi.Group) {
+		r.GET("/users/{id}", func() resOK { return resOK{} })
+		r.POST("/users/{id}", func() resOK { return resOK{} })
+		r.DELETE("/users/me", func() resOK { return resOK{} })
+	})
+
+	res := g.Req(http.MethodPut, tr.URL("/users/me"))
+	g.Eq(res.StatusCode, http.StatusMethodNotAllowed)
+	g.Eq(res.Header.Get("Allow"), "GET, POST, DELETE")
+	g.Eq(res.JSON(), map[string]any{
+		"error": map[string]any{
+			"code":       "method_not_allowed",
+			"message":    "method not allowed: PUT /users/me",
+			"target":     "/users/me",
+			"innererror": []any{"GET", "POST", "DELETE"},
+		},
+	})
+
+	res = g.Req(http.MethodDelete, tr.URL("/users/1"))
+	g.Eq(res.StatusCode, http.StatusMethodNotAllowed)
+	g.Eq(res.Header.Get("Allow"), "GET, POST")
+
+	g.Eq(g.Req(http.MethodGet, tr.URL("/users")).StatusCode, http.StatusNotFound)
+}
+
+func TestAutoMethods(t *testing.T) {
+	g := got.T(t)
+
+	r := goapi.New()
+	r.Router().AutoHEAD = true
+	r.Router().AutoOPTIONS = true
+
+	r.GET("/users/{id}", func() resOK { return resOK{Data: "ok"} })
+	r.POST("/users/{id}", func() resOK { return resOK{} })
+	r.HEAD("/posts", func() goapi.StatusNoContent { return goapi.StatusNoContent{} })
+	r.GET("/posts", func() resOK { return resOK{Data: "ok"} })
+
+	tr := g.Serve()
+	tr.Mux.Handle("/", r.Server())
+
+	res := g.Req(http.MethodHead, tr.URL("/users/1"))
+	g.Eq(res.StatusCode, http.StatusOK)
+	g.Eq(res.Header.Get("Content-Length"), "13")
+	g.Eq(res.String(), "")
+
+	g.Eq(g.Req(http.MethodHead, tr.URL("/posts")).StatusCode, http.StatusNoContent)
+
+	res = g.Req(http.MethodOptions, tr.URL("/users/1"))
+	g.Eq(res.StatusCode, http.StatusNoContent)
+	g.Eq(res.Header.Get("Allow"), "GET, POST, HEAD, OPTIONS")
+
+	g.Eq(g.Req(http.MethodOptions, tr.URL("/x")).StatusCode, http.StatusNotFound)
+
+	res = g.Req(http.MethodPut, tr.URL("/users/1"))
+	g.Eq(res.StatusCode, http.StatusMethodNotAllowed)
+	g.Eq(res.Header.Get("Allow"), "GET, POST, HEAD, OPTIONS")
+}
+
+func getUser() resOK { return resOK{} }
+
+func getUserByName() resOK { return resOK{} }
+
+func getFiles() resOK { return resOK{} }
+
+func serveFiles(http.ResponseWriter, *http.Request) {}
+
+func TestRouteConflict(t *testing.T) {
+	g := got.T(t)
+
+	r := goapi.New()
+
+	r.GET("/users/{id}", getUser)
+	r.POST("/users/{id}", getUser)
+	r.GET("/users/me", getUser)
+	r.GET("/files/*", getFiles)
+	r.GET("/files/{name}", getUser)
+
+	g.Eq(g.Panic(func() {
+		r.GET("/users/{id}", getUserByName)
+	}), "route GET /users/{id} (handler getUserByName) conflicts with route GET /users/{id} (handler getUser)")
+
+	g.Eq(g.Panic(func() {
+		r.GET("/users/{name}", getUserByName)
+	}), "route GET /users/{name} (handler getUserByName) conflicts with route GET /users/{id} (handler getUser)")
+
+	g.Eq(g.Panic(func() {
+		r.GET("/files/*", serveFiles)
+	}), "route GET /files/* (handler serveFiles) conflicts with route GET /files/* (handler getFiles)")
+
+	g.Eq(g.Panic(func() {
+		r.GET("/users/{id:int}", getUserByName)
+	}), "route GET /users/{id:int} (handler getUserByName) and route GET /users/{id} (handler getUser) "+
+		"have the same openapi path /users/{id}")
+
+	g.Eq(g.Panic(func() {
+		r.GET("/users/{name:[a-z]+}", getUserByName)
+	}), "route GET /users/{name:[a-z]+} (handler getUserByName) and route GET /users/{id} (handler getUser) "+
+		"have the same openapi path /users/{name}")
+
+	g.Eq(g.Panic(func() {
+		r.DELETE("/users/{name}", getUserByName)
+	}), "route DELETE /users/{name} (handler getUserByName) and route GET /users/{id} (handler getUser) "+
+		"have the same openapi path /users/{id}, their path parameters must have the same names")
+
+	r.DELETE("/users/{id:int}", getUser)
+
+	r.POST("/users/{id:int}/raw", getUser)
+
+	r.Router().StrictRoutes = true
+
+	g.Eq(g.Panic(func() {
+		r.GET("/files/{name}/raw", getUser)
+	}), "route GET /files/{name}/raw (handler getUser) and route GET /files/* (handler getFiles) shadow each other")
+
+	g.Eq(g.Panic(func() {
+		r.GET("/users/*", getFiles)
+	}), "route GET /users/* (handler getFiles) and route GET /users/{id} (handler getUser) shadow each other")
+
+	r.POST("/files/{name}/raw", getUser)
+}
+
+func TestTypedPathParams(t *testing.T) {
+	g := got.T(t)
+
+	r := goapi.New()
+
+	r.GET("/users/{id:int}", func(p struct {
+		goapi.InURL
+		ID int
+	}) resOK {
+		return resOK{Data: "id"}
+	})
+
+	r.GET("/users/*", func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte("fallback"))
+	})
+
+	r.GET("/names/{name:a|bc}", func(p struct {
+		goapi.InURL
+		Name string
+	}) resOK {
+		return resOK{Data: p.Name}
+	})
+
+	r.GET("/files/{file...}", func(p struct {
+		goapi.InURL
+		File string
+	}) resOK {
+		return resOK{Data: p.File}
+	})
+
+	tr := g.Serve()
+	tr.Mux.Handle("/", r.Server())
+
+	g.Eq(g.Req("", tr.URL("/users/1")).JSON(), map[string]any{"data": "id"})
+	g.Eq(g.Req("", tr.URL("/users/me")).String(), "fallback")
+	g.Eq(g.Req("", tr.URL("/names/bc")).JSON(), map[string]any{"data": "bc"})
+	g.Eq(g.Req("", tr.URL("/names/ab")).StatusCode, http.StatusNotFound)
+	g.Eq(g.Req("", tr.URL("/files/a/b.txt")).JSON(), map[string]any{"data": "a/b.txt"})
+
+	doc := r.OpenAPI()
+	g.Eq(doc.Paths["/names/{name}"][openapi.GET].Parameters[0].Schema.Pattern, "^(?:a|bc)$")
+	g.Eq(doc.Paths["/users/{id}"][openapi.GET].Parameters[0].Schema.Type, jschema.TypeInteger)
+	g.Len(doc.Paths["/files/{file}"], 1)
+}
+
+func TestRoutes(t *testing.T) {
+	g := got.T(t)
+
+	team := goapi.New()
+	team.GET("/members", getUser)
+
+	r := goapi.New()
+	r.Group("/users").GET("/{id:int}", getUser)
+	r.POST("/files/*", serveFiles)
+	r.Group("/api").Mount("/team", team.Router())
+	r.GET("/res", func(context.Context) res { return resOK{} })
+	r.GET("/raw/*", serveFiles)
+
+	routes := r.Router().Routes()
+	g.Len(routes, 5)
+
+	g.Eq(routes[0].Method, openapi.GET)
+	g.Eq(routes[0].Path, "/users/{id:int}")
+	g.Eq(routes[0].Name, "getUser")
+	g.Eq(routes[0].Prefix, "/users")
+	g.Eq(routes[0].Handler, "github.com/NaturalSelectionLabs/goapi_test.getUser")
+	g.Has(routes[0].File, "router_test.go")
+	g.Gt(routes[0].Line, 0)
+	g.Len(routes[0].Params, 0)
+	g.Eq(routes[0].Responses, []reflect.Type{reflect.TypeOf(resOK{})})
+
+	g.Eq(routes[1].Name, "")
+	g.Eq(routes[1].Handler, "github.com/NaturalSelectionLabs/goapi_test.serveFiles")
+	g.Nil(routes[1].Responses)
+
+	g.Eq(routes[2].Path, "/api/team/members")
+	g.Eq(routes[2].Prefix, "/api/team")
+
+	g.Eq(routes[3].Params, []reflect.Type{reflect.TypeOf(new(context.Context)).Elem()})
+	g.Eq(routes[3].Responses, []reflect.Type{reflect.TypeOf(resOK{})})
+
+	g.Eq(routes[4].Path, "/raw/*")
+}
+
+func TestDynamicRoutes(t *testing.T) {
+	g := got.T(t)
+
+	r := goapi.New()
+	a := r.GET("/a", func() resOK { return resOK{Data: "a"} })
+
+	tr := g.Serve()
+	tr.Mux.Handle("/", r.Server())
+
+	wg := sync.WaitGroup{}
+
+	for i := 0; i < 10; i++ {
+		wg.Add(3)
+
+		go func() {
+			defer wg.Done()
+			g.Eq(g.Req("", tr.URL("/a")).StatusCode, http.StatusOK)
+		}()
+
+		go func(i int) {
+			defer wg.Done()
+			r.GET(fmt.Sprintf("/b%d", i), func() resOK { return resOK{Data: "b"} })
+		}(i)
+
+		go func() {
+			defer wg.Done()
+			a.Use(middlewares.Func(func(next http.Handler) http.Handler { return next }))
+			a.OpenAPI(func(doc *openapi.Operation) {})
+		}()
+	}
+
+	wg.Wait()
+
+	g.Eq(g.Req("", tr.URL("/b3")).JSON(), map[string]any{"data": "b"})
+
+	r.Router().Use(middlewares.Func(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("x-new", "ok")
+			next.ServeHTTP(w, r)
+		})
+	}))
+
+	c := r.GET("/c", func() resOK { return resOK{Data: "c"} })
+
+	res := g.Req("", tr.URL("/c"))
+	g.Eq(res.JSON(), map[string]any{"data": "c"})
+	g.Eq(res.Header.Get("x-new"), "ok")
+
+	c.Disable()
+	g.Eq(g.Req("", tr.URL("/c")).StatusCode, http.StatusNotFound)
+	g.Len(r.OpenAPI().Paths["/c"], 0)
+	g.True(r.Router().Routes()[11].Disabled)
+
+	c.Enable()
+	g.Eq(g.Req("", tr.URL("/c")).StatusCode, http.StatusOK)
+	g.Len(r.OpenAPI().Paths["/c"], 1)
+
+	c.Remove()
+	g.Eq(g.Req("", tr.URL("/c")).StatusCode, http.StatusNotFound)
+	g.Len(r.Router().Routes(), 11)
+
+	r.GET("/c", func() resOK { return resOK{Data: "c2"} })
+	g.Eq(g.Req("", tr.URL("/c")).JSON(), map[string]any{"data": "c2"})
+
+	once := sync.Once{}
+	a.OpenAPI(func(doc *openapi.Operation) {
+		once.Do(func() {
+			r.GET("/d", func() resOK { return resOK{Data: "d"} })
+		})
+	})
+	g.Len(r.OpenAPI().Paths["/d"], 0)
+	g.Len(r.OpenAPI().Paths["/d"], 1)
+}
@@ -0,0 +1,114 @@
+package goapi
+
+import (
+	"reflect"
+	"runtime"
+	"sort"
+
+	"github.com/NaturalSelectionLabs/goapi/lib/openapi"
+	"github.com/naturalselectionlabs/vary"
+)
+
+// Route is the summary of a registered operation, check [Router.Routes].
+type Route struct {
+	Method openapi.Method
+
+	// Path is the path pattern of the operation, such as "/users/{id:int}".
+	Path string
+
+	// Name is the operation name, it's empty if the handler is a [http.HandlerFunc].
+	Name string
+
+	// Prefix is the prefix of the group that the operation belongs to.
+	Prefix string
+
+	// Handler is the full name of the handler function, such as "main.getUser".
+	Handler string
+
+	// File and Line are the source location of the handler function.
+	File string
+	Line int
+
+	// Params are the parameter types of the handler function.
+	Params []reflect.Type
+
+	// Responses are all the possible response types of the handler function.
+	Responses []reflect.Type
+
+	// Disabled is true if the operation is disabled by [Operation.Disable].
+	Disabled bool
+}
+
+// Routes returns the summary of all the registered operations in the order they are added,
+// including the operations of the routers mounted by [Group.Mount] at the position of the mount.
+func (r *Router) Routes() []Route {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list := []Route{}
+	listed := map[*mount]bool{}
+
+	for _, op := range r.operations {
+		if op.mounted == nil {
+			list = append(list, op.route())
+			continue
+		}
+
+		// The mount adds an operation for each method, only list the mounted routes once.
+		if listed[op.mounted] {
+			continue
+		}
+
+		listed[op.mounted] = true
+
+		for _, route := range op.mounted.router.Routes() {
+			route.Path = op.mounted.prefix + route.Path
+			route.Prefix = op.mounted.prefix + route.Prefix
+			list = append(list, route)
+		}
+	}
+
+	return list
+}
+
+func (op *Operation) route() Route {
+	fn := runtime.FuncForPC(op.vHandler.Pointer())
+	file, line := fn.FileLine(fn.Entry())
+
+	route := Route{
+		Method:   op.method,
+		Path:     op.path.path,
+		Name:     op.name,
+		Prefix:   op.group.prefix,
+		Handler:  fn.Name(),
+		File:     file,
+		Line:     line,
+		Disabled: op.disabled.Load(),
+	}
+
+	if op.override != nil {
+		return route
+	}
+
+	for i := 0; i < op.tHandler.NumIn(); i++ {
+		route.Params = append(route.Params, op.tHandler.In(i))
+	}
+
+	if op.tRes == nil {
+		return route
+	}
+
+	if it, has := Interfaces[vary.ID(op.tRes)]; has {
+		for _, t := range it.Implementations {
+			route.Responses = append(route.Responses, t)
+		}
+
+		sort.Slice(route.Responses, func(i, j int) bool {
+			return route.Responses[i].String() < route.Responses[j].String()
+		})
+	} else {
+		route.Responses = append(route.Responses, op.tRes)
+	}
+
+	return route
+}
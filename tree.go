@@ -0,0 +1,264 @@
+package goapi
+
+import (
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"github.com/NaturalSelectionLabs/goapi/lib/middlewares"
+	"github.com/NaturalSelectionLabs/goapi/lib/openapi"
+)
+
+// routeTree is a trie of path segments that resolves the method and path of a request
+// to an operation with a single lookup, no matter how many operations are registered.
+// Static segments have higher priority than parameter segments, wildcard has the lowest.
+// Parameter segments are tried in the order they are added, a value that doesn't match the constraint
+// of a parameter falls through to the next one.
+// The trie is immutable once built, any change to the operations invalidates it,
+// the next lookup builds a new trie and swaps it in atomically.
+type routeTree struct {
+	router *Router
+	root   atomic.Pointer[routeNode]
+
+	// operations are guarded by the lock of the router.
+	operations []*Operation
+}
+
+var _ middlewares.Middleware = (*routeTree)(nil)
+
+type routeNode struct {
+	segment *pathSegment
+
+	static   map[string]*routeNode
+	params   []*routeNode
+	wildcard *routeNode
+
+	operations map[openapi.Method]*Operation
+}
+
+func newRouteTree(r *Router) *routeTree {
+	return &routeTree{router: r}
+}
+
+func newRouteNode(s *pathSegment) *routeNode {
+	return &routeNode{
+		segment:    s,
+		static:     map[string]*routeNode{},
+		operations: map[openapi.Method]*Operation{},
+	}
+}
+
+// add must be called with the lock of the router held.
+func (t *routeTree) add(op *Operation) {
+	op.tree = t
+	t.operations = append(t.operations, op)
+	t.invalidate()
+}
+
+// remove must be called with the lock of the router held.
+func (t *routeTree) remove(op *Operation) {
+	for i, o := range t.operations {
+		if o == op {
+			t.operations = append(t.operations[:i:i], t.operations[i+1:]...)
+			break
+		}
+	}
+
+	t.invalidate()
+}
+
+func (t *routeTree) invalidate() {
+	t.root.Store(nil)
+}
+
+// load the trie, build it from the enabled operations if it's invalidated.
+func (t *routeTree) load() *routeNode {
+	if root := t.root.Load(); root != nil {
+		return root
+	}
+
+	t.router.mu.Lock()
+	defer t.router.mu.Unlock()
+
+	if root := t.root.Load(); root != nil {
+		return root
+	}
+
+	root := newRouteNode(nil)
+
+	for _, op := range t.operations {
+		if !op.disabled.Load() {
+			root.insert(op)
+		}
+	}
+
+	t.root.Store(root)
+
+	return root
+}
+
+func (n *routeNode) insert(op *Operation) {
+	for _, s := range op.path.segments {
+		n = n.child(s)
+	}
+
+	if op.path.wildcard {
+		if n.wildcard == nil {
+			n.wildcard = newRouteNode(nil)
+		}
+
+		n = n.wildcard
+	}
+
+	if _, has := n.operations[op.method]; !has {
+		n.operations[op.method] = op
+	}
+}
+
+func (n *routeNode) child(s *pathSegment) *routeNode {
+	if s.isStatic() {
+		if c, has := n.static[s.raw]; has {
+			return c
+		}
+
+		c := newRouteNode(s)
+		n.static[s.raw] = c
+
+		return c
+	}
+
+	for _, c := range n.params {
+		if c.segment.raw == s.raw {
+			return c
+		}
+	}
+
+	c := newRouteNode(s)
+	n.params = append(n.params, c)
+
+	return c
+}
+
+// find the operation for the method and path, it returns the path parameters as name value pairs.
+// If the path has no trailing slash and nothing matches, it will retry with a trailing slash.
+func (t *routeTree) find(method openapi.Method, path string) (*Operation, []string) {
+	ok := func(n *routeNode) bool {
+		_, has := n.operations[method]
+		return has
+	}
+
+	for {
+		params := []string{}
+
+		if n := t.load().lookup(splitPath(path), &params, ok); n != nil {
+			return n.operations[method], params
+		}
+
+		if strings.HasSuffix(path, "/") {
+			return nil, nil
+		}
+
+		path += "/"
+	}
+}
+
+// methods adds all the methods registered for the path to the set.
+func (t *routeTree) methods(path string, set map[openapi.Method]bool) {
+	// Always return false to visit all the nodes that match the path.
+	collect := func(n *routeNode) bool {
+		for m := range n.operations {
+			set[m] = true
+		}
+
+		return false
+	}
+
+	root := t.load()
+
+	root.lookup(splitPath(path), &[]string{}, collect)
+
+	if !strings.HasSuffix(path, "/") {
+		root.lookup(splitPath(path+"/"), &[]string{}, collect)
+	}
+}
+
+// lookup does a depth-first search for the node that satisfies ok.
+func (n *routeNode) lookup(segments []string, params *[]string, ok func(*routeNode) bool) *routeNode {
+	if len(segments) == 0 {
+		if ok(n) {
+			return n
+		}
+
+		if n.wildcard != nil && ok(n.wildcard) {
+			*params = append(*params, "*", "")
+			return n.wildcard
+		}
+
+		return nil
+	}
+
+	if c, has := n.static[segments[0]]; has {
+		if found := c.lookup(segments[1:], params, ok); found != nil {
+			return found
+		}
+	}
+
+	l := len(*params)
+
+	for _, c := range n.params {
+		v, rest := segments[0], segments[1:]
+
+		if c.segment.rest {
+			v, rest = strings.Join(segments, "/"), nil
+		}
+
+		if c.segment.match(v, params) {
+			if found := c.lookup(rest, params, ok); found != nil {
+				return found
+			}
+		}
+
+		*params = (*params)[:l]
+	}
+
+	if n.wildcard != nil && ok(n.wildcard) {
+		*params = append(*params, "*", strings.Join(segments, "/"))
+		return n.wildcard
+	}
+
+	return nil
+}
+
+// Handler implements the [middlewares.Middleware] interface.
+func (t *routeTree) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		method, err := openapi.MethodString(r.Method)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		op, params := t.find(method, r.URL.Path)
+
+		if op == nil && method == openapi.HEAD && t.router.AutoHEAD {
+			op, params = t.find(openapi.GET, r.URL.Path)
+		}
+
+		if op == nil && method == openapi.OPTIONS && t.router.AutoOPTIONS {
+			set := map[openapi.Method]bool{}
+			if t.methods(r.URL.Path, set); len(set) > 0 {
+				w.Header().Set("Allow", strings.Join(t.router.allowedMethods(r.URL.Path, true), ", "))
+				w.WriteHeader(http.StatusNoContent)
+
+				return
+			}
+		}
+
+		if op == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		op.serve(w, r, params)
+	})
+}
@@ -0,0 +1,97 @@
+package goapi
+
+import (
+	"testing"
+
+	"github.com/NaturalSelectionLabs/goapi/lib/openapi"
+	"github.com/ysmood/got"
+)
+
+func TestRouteTree(t *testing.T) {
+	g := got.T(t)
+
+	tree := newRouteTree(NewRouter())
+
+	add := func(method openapi.Method, path string) *Operation {
+		p, err := newPath(path, false)
+		g.E(err)
+
+		op := &Operation{method: method, path: p}
+		tree.add(op)
+
+		return op
+	}
+
+	users := add(openapi.GET, "/users")
+	user := add(openapi.GET, "/users/{id}")
+	me := add(openapi.GET, "/users/me")
+	posts := add(openapi.GET, "/users/{id}/posts/{post}")
+	file := add(openapi.GET, "/files/{name}.json")
+	wildcard := add(openapi.GET, "/files/*")
+	dir := add(openapi.POST, "/dir/")
+	root := add(openapi.GET, "/")
+	num := add(openapi.GET, "/posts/{id:int}")
+	slug := add(openapi.GET, "/posts/{slug:[a-z-]+}")
+	rest := add(openapi.GET, "/docs/{file...}")
+
+	op, params := tree.find(openapi.GET, "/users")
+	g.Eq(op, users)
+	g.Eq(params, []string{})
+
+	op, params = tree.find(openapi.GET, "/users/me")
+	g.Eq(op, me)
+	g.Eq(params, []string{})
+
+	op, params = tree.find(openapi.GET, "/users/1")
+	g.Eq(op, user)
+	g.Eq(params, []string{"id", "1"})
+
+	op, params = tree.find(openapi.GET, "/users/me/posts/2")
+	g.Eq(op, posts)
+	g.Eq(params, []string{"id", "me", "post", "2"})
+
+	op, params = tree.find(openapi.GET, "/files/a.json")
+	g.Eq(op, file)
+	g.Eq(params, []string{"name", "a"})
+
+	op, params = tree.find(openapi.GET, "/files/a/b.txt")
+	g.Eq(op, wildcard)
+	g.Eq(params, []string{"*", "a/b.txt"})
+
+	op, params = tree.find(openapi.GET, "/files")
+	g.Eq(op, wildcard)
+	g.Eq(params, []string{"*", ""})
+
+	op, _ = tree.find(openapi.POST, "/dir")
+	g.Eq(op, dir)
+
+	op, _ = tree.find(openapi.GET, "")
+	g.Eq(op, root)
+
+	op, params = tree.find(openapi.GET, "/posts/12")
+	g.Eq(op, num)
+	g.Eq(params, []string{"id", "12"})
+
+	op, params = tree.find(openapi.GET, "/posts/a-b")
+	g.Eq(op, slug)
+	g.Eq(params, []string{"slug", "a-b"})
+
+	op, _ = tree.find(openapi.GET, "/posts/A")
+	g.Nil(op)
+
+	op, params = tree.find(openapi.GET, "/docs/a/b.md")
+	g.Eq(op, rest)
+	g.Eq(params, []string{"file", "a/b.md"})
+
+	op, _ = tree.find(openapi.GET, "/docs/")
+	g.Nil(op)
+
+	op, _ = tree.find(openapi.POST, "/users")
+	g.Nil(op)
+
+	op, _ = tree.find(openapi.GET, "/users/")
+	g.Nil(op)
+
+	op, _ = tree.find(openapi.GET, "/x")
+	g.Nil(op)
+}
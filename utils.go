@@ -37,6 +37,20 @@ func toValue(t reflect.Type, val string) (reflect.Value, error) {
 	return v.Elem(), nil
 }
 
+// toString is the reverse of [toValue], such as a time.Time is formatted as RFC 3339.
+func toString(v reflect.Value) (string, error) {
+	b, err := json.Marshal(v.Interface())
+	if err != nil {
+		return "", err
+	}
+
+	if len(b) > 0 && b[0] == '"' {
+		return strconv.Unquote(string(b))
+	}
+
+	return string(b), nil
+}
+
 func tagName(t reflect.StructTag, name string) string {
 	tag := jschema.ParseJSONTag(t)
 
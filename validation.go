@@ -0,0 +1,140 @@
+package goapi
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/NaturalSelectionLabs/goapi/lib/openapi"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// paramsError is the aggregated errors of the params of a request,
+// each of them is responded as one of the [openapi.Error.Details].
+type paramsError []openapi.CommonError[openapi.Code]
+
+func (e paramsError) Error() string {
+	list := make([]string, 0, len(e))
+
+	for _, d := range e {
+		list = append(list, d.Message)
+	}
+
+	return strings.Join(list, "; ")
+}
+
+func (e *paramsError) add(code openapi.Code, target, message string) {
+	*e = append(*e, openapi.CommonError[openapi.Code]{
+		Code:    code,
+		Target:  target,
+		Message: message,
+	})
+}
+
+// err returns nil if there's no error.
+func (e paramsError) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+
+	return e
+}
+
+// validationCodes maps the types of [gojsonschema.ResultError] to the codes of [openapi.Error.Details].
+var validationCodes = map[string]openapi.Code{
+	"required":             openapi.CodeRequired,
+	"pattern":              openapi.CodePattern,
+	"format":               openapi.CodeFormat,
+	"invalid_type":         openapi.CodeType,
+	"enum":                 openapi.CodeEnum,
+	"const":                openapi.CodeEnum,
+	"number_gte":           openapi.CodeMin,
+	"number_gt":            openapi.CodeMin,
+	"string_gte":           openapi.CodeMin,
+	"array_min_items":      openapi.CodeMin,
+	"array_min_properties": openapi.CodeMin,
+	"number_lte":           openapi.CodeMax,
+	"number_lt":            openapi.CodeMax,
+	"string_lte":           openapi.CodeMax,
+	"array_max_items":      openapi.CodeMax,
+	"array_max_properties": openapi.CodeMax,
+}
+
+// addResult adds the errors of a json schema validation result, the message of each error is prefixed by prefix
+// and the field of the error if it's not the root. The errors are sorted by target,
+// because the order of the result is random. Duplicated errors are dropped, the errors nested in an optional
+// value are reported by both the anyOf branch and the schema itself.
+func (e *paramsError) addResult(res *gojsonschema.Result, prefix string, target func(gojsonschema.ResultError) string) {
+	start := len(*e)
+	type key struct {
+		code            openapi.Code
+		target, message string
+	}
+
+	seen := map[key]bool{}
+
+	for _, re := range res.Errors() {
+		// The error of an optional value is the mismatch of the schema or null,
+		// the detailed errors of the schema are already reported.
+		if re.Type() == "number_any_of" {
+			continue
+		}
+
+		if re.Type() == "invalid_type" && re.Details()["expected"] == "null" {
+			continue
+		}
+
+		code, has := validationCodes[re.Type()]
+		if !has {
+			code = openapi.CodeInvalidParam
+		}
+
+		msg := prefix + re.Description()
+
+		if re.Field() != gojsonschema.STRING_ROOT_SCHEMA_PROPERTY {
+			msg = prefix + re.Field() + ": " + re.Description()
+		}
+
+		k := key{code, target(re), msg}
+		if seen[k] {
+			continue
+		}
+
+		seen[k] = true
+
+		e.add(k.code, k.target, k.message)
+	}
+
+	list := (*e)[start:]
+	sort.SliceStable(list, func(i, j int) bool { return list[i].Target < list[j].Target })
+}
+
+// jsonPointer returns the json pointer of the value of a validation error, such as "/user/name".
+func jsonPointer(re gojsonschema.ResultError) string {
+	p := strings.TrimPrefix(re.Context().String("/"), gojsonschema.STRING_CONTEXT_ROOT)
+
+	if re.Type() == "required" {
+		if prop, ok := re.Details()["property"].(string); ok {
+			p += "/" + prop
+		}
+	}
+
+	if p == "" {
+		return "/"
+	}
+
+	return p
+}
+
+// fieldPointer converts the dotted field path of a [json.UnmarshalTypeError] to a json pointer, such as "/user/name".
+func fieldPointer(field string) string {
+	if field == "" {
+		return "/"
+	}
+
+	list := strings.Split(field, ".")
+	for i, f := range list {
+		list[i] = strings.NewReplacer("~", "~0", "/", "~1").Replace(f)
+	}
+
+	return "/" + strings.Join(list, "/")
+}